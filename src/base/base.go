@@ -23,44 +23,43 @@
  * ...
 **/
 
-package dataset
+package base
 
 import (
     "fmt"
-    "tools/share"
-)
 
-const (
-    FindIsDef bool = true
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
 )
 
 // -----------------------------------------------------------------------------
 
-type DataSet struct {
+type DataSet[K any, V any] struct {
 }
 
 // -----------------------------------------------------------------------------
 
-func New() *DataSet {
+func New[K any, V any](opts share.Options) (*DataSet[K, V], error) { // No option used
     fmt.Println("Please implement me!")
-    return new(DataSet)
+    return new(DataSet[K, V]), nil
 }
 
-func (set *DataSet) Destroy() {
+func (set *DataSet[K, V]) Destroy() {
 }
 
-func (set *DataSet) Size() uint {
+func (set *DataSet[K, V]) Size() uint {
     return 0
 }
 
-func (set *DataSet) Find(key share.Key) (share.Val, bool) {
-    return 0, false
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    var zero V
+    return zero, false
 }
 
-func (set *DataSet) Insert(key share.Key, val share.Val) bool {
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
     return false
 }
 
-func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
-    return 0, false
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    var zero V
+    return zero, false
 }
@@ -0,0 +1,299 @@
+/**
+ * @file   bst_ellen.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Non-blocking Binary Search Trees,
+ * Faith Ellen, Panagiota Fatourou, Eric Ruppert, Franck van Breugel,
+ * PODC 2010.
+ *
+ * External tree: the elements are stored in the leaves. Each internal node has
+ * an update field, pointing to the state of the node (clean, flagged for an
+ * insertion or a deletion, or marked) and to the record of the operation
+ * flagging or marking it, so that any thread can help this operation complete.
+ * The update field and the records are immutable once published, so that each
+ * change of state allocates a new update field, compared by address.
+**/
+
+package bst_ellen
+
+import (
+    "cmp"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    state_clean = iota
+    state_dflag // The node is the grandparent of a leaf being deleted
+    state_iflag // The node is the parent of a leaf being replaced by an insertion
+    state_mark  // The node is the parent of a leaf being deleted, and will be removed with it
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    bound share.Bound                   // BOUND_MAX for the sentinels, BOUND_NONE otherwise
+    child [2]atomic.Pointer[node[K, V]] // Left and right children, both nil for a leaf
+    update atomic.Pointer[update[K, V]] // State of an internal node, nil for a leaf
+}
+
+// State of an internal node, and operation to help if not clean
+type update[K cmp.Ordered, V any] struct {
+    state int
+    info *info[K, V]
+}
+
+// Record of an insertion ('p', 'l' and 'add') or a deletion ('gp', 'p', 'l', 'pupdate'), shared with the helpers
+type info[K cmp.Ordered, V any] struct {
+    gp *node[K, V]          // Grandparent of the leaf (deletion)
+    p *node[K, V]           // Parent of the leaf
+    l *node[K, V]           // Leaf, replaced (insertion) or deleted (deletion)
+    add *node[K, V]         // New internal node, replacing the leaf (insertion)
+    pupdate *update[K, V]   // State of the parent when the deletion started (deletion)
+    flag *update[K, V]      // Update field flagging the node ('p' for an insertion, 'gp' for a deletion)
+}
+
+// Result of a search: the leaf reached, its parent and grandparent, and the update fields read on these nodes
+type search_record[K cmp.Ordered, V any] struct {
+    gp, p, l *node[K, V]
+    gpupdate, pupdate *update[K, V]
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    root *node[K, V]       // Internal sentinel, the parent of the leftmost leaf (another sentinel)
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) side(key K) int { // Index of the child followed by the search for 'key'
+    if n.bound == share.BOUND_MAX || key < n.key {
+        return 0
+    }
+    return 1
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) is_leaf() bool {
+    return n.update.Load() == nil
+}
+
+func new_leaf[K cmp.Ordered, V any](key K, val V) *node[K, V] {
+    node := new(node[K, V])
+    node.key = key
+    node.val = val
+    return node
+}
+
+func new_internal[K cmp.Ordered, V any](left *node[K, V], right *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.child[0].Store(left)
+    node.child[1].Store(right)
+    node.update.Store(&update[K, V]{state: state_clean})
+    return node
+}
+
+func new_replacement[K cmp.Ordered, V any](leaf *node[K, V], key K, val V) *node[K, V] { // Replacement of 'leaf', routing to both 'leaf' and a new leaf of 'key'
+    add := new_leaf(key, val)
+    if leaf.side(key) == 0 {
+        node := new_internal(add, leaf)
+        node.key = leaf.key
+        node.bound = leaf.bound
+        return node
+    }
+    node := new_internal(leaf, add)
+    node.key = key
+    return node
+}
+
+func cas_child[K cmp.Ordered, V any](parent *node[K, V], old *node[K, V], new *node[K, V]) { // Replace the child 'old' of 'parent', if still there
+    side := 1
+    if parent.child[0].Load() == old {
+        side = 0
+    }
+    parent.child[side].CompareAndSwap(old, new)
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) search(key K, rec *search_record[K, V]) {
+    rec.gp = nil
+    rec.p = nil
+    rec.l = set.root
+    rec.gpupdate = nil
+    rec.pupdate = nil
+    for !rec.l.is_leaf() {
+        rec.gp = rec.p
+        rec.p = rec.l
+        rec.gpupdate = rec.pupdate
+        rec.pupdate = rec.p.update.Load()
+        rec.l = rec.p.child[rec.p.side(key)].Load()
+    }
+}
+
+func help[K cmp.Ordered, V any](u *update[K, V]) {
+    switch u.state {
+    case state_iflag:
+        help_insert(u.info)
+    case state_mark:
+        help_marked(u.info)
+    case state_dflag:
+        help_delete(u.info)
+    }
+}
+
+func help_insert[K cmp.Ordered, V any](op *info[K, V]) {
+    cas_child(op.p, op.l, op.add)
+    op.p.update.CompareAndSwap(op.flag, &update[K, V]{state: state_clean, info: op})
+}
+
+func help_delete[K cmp.Ordered, V any](op *info[K, V]) bool { // Mark the parent, or backtrack if it changed meanwhile
+    mark := &update[K, V]{state: state_mark, info: op}
+    if !op.p.update.CompareAndSwap(op.pupdate, mark) {
+        if seen := op.p.update.Load(); seen.state != state_mark || seen.info != op {
+            help(seen)
+            op.gp.update.CompareAndSwap(op.flag, &update[K, V]{state: state_clean, info: op})
+            return false
+        }
+    }
+    help_marked(op)
+    return true
+}
+
+func help_marked[K cmp.Ordered, V any](op *info[K, V]) {
+    other := op.p.child[1].Load()
+    if other == op.l {
+        other = op.p.child[0].Load()
+    }
+    cas_child(op.gp, op.p, other)
+    op.gp.update.CompareAndSwap(op.flag, &update[K, V]{state: state_clean, info: op})
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    var key K
+    var val V
+    min := new_leaf(key, val)
+    min.bound = share.BOUND_MAX
+    max := new_leaf(key, val)
+    max.bound = share.BOUND_MAX
+    set.root = new_internal(min, max)
+    set.root.bound = share.BOUND_MAX
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    stack := []*node[K, V]{set.root}
+    for len(stack) > 0 {
+        node := stack[len(stack) - 1]
+        stack = stack[:len(stack) - 1]
+        if node.is_leaf() {
+            if node.bound == share.BOUND_NONE {
+                size++
+            }
+            continue
+        }
+        stack = append(stack, node.child[1].Load(), node.child[0].Load())
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    curr := set.root
+    for !curr.is_leaf() {
+        curr = curr.child[curr.side(key)].Load()
+    }
+    if curr.equal(key) {
+        return curr.val, true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var rec search_record[K, V]
+    for {
+        set.search(key, &rec)
+        if rec.l.equal(key) {
+            return false
+        }
+        if rec.pupdate.state != state_clean {
+            help(rec.pupdate)
+            continue
+        }
+        op := &info[K, V]{p: rec.p, l: rec.l, add: new_replacement(rec.l, key, val)}
+        op.flag = &update[K, V]{state: state_iflag, info: op}
+        if rec.p.update.CompareAndSwap(rec.pupdate, op.flag) {
+            help_insert(op)
+            set.count.Add(1)
+            return true
+        }
+        help(rec.p.update.Load())
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    var rec search_record[K, V]
+    for {
+        set.search(key, &rec)
+        if !rec.l.equal(key) { // A real leaf always has a grandparent, the root being the parent of a sentinel
+            var zero V
+            return zero, false
+        }
+        if rec.gpupdate.state != state_clean {
+            help(rec.gpupdate)
+            continue
+        }
+        if rec.pupdate.state != state_clean {
+            help(rec.pupdate)
+            continue
+        }
+        op := &info[K, V]{gp: rec.gp, p: rec.p, l: rec.l, pupdate: rec.pupdate}
+        op.flag = &update[K, V]{state: state_dflag, info: op}
+        if rec.gp.update.CompareAndSwap(rec.gpupdate, op.flag) {
+            if help_delete(op) {
+                set.count.Add(-1)
+                return rec.l.val, true
+            }
+            continue
+        }
+        help(rec.gp.update.Load())
+    }
+}
@@ -0,0 +1,384 @@
+/**
+ * @file   bst_howley.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * A non-blocking internal binary search tree,
+ * Shane V. Howley and Jeremy Jones,
+ * SPAA 2012.
+ *
+ * Internal tree: every node holds an element. Each node has an operation field,
+ * flagged with the kind of operation in progress (child CAS, relocation or
+ * mark), that the other threads help complete before changing the node. A node
+ * with two children is deleted by relocating the element of its successor into
+ * it, then removing the successor. As the keys and values cannot be swapped by a
+ * CAS, each element is boxed, the relocation replacing the box of the node.
+ * A null child pointer is flagged with the node it was cleared from, so that a
+ * late child CAS never succeeds on a null pointer that was reset meanwhile.
+**/
+
+package bst_howley
+
+import (
+    "cmp"
+    "sync/atomic"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    flag_none = uintptr(0)
+    flag_mark = uintptr(1)     // The node is being removed
+    flag_childcas = uintptr(2) // A child of the node is being replaced
+    flag_relocate = uintptr(3) // The element of the node is being relocated into another node
+    flag_bits = uintptr(3)
+    null_bit = uintptr(1)      // The child pointer is null, the node pointed being the one removed from there
+)
+
+const (
+    state_ongoing = iota
+    state_successful
+    state_failed
+)
+
+const (
+    found = iota
+    notfound_l // Not found, the last node visited having no left child
+    notfound_r // Not found, the last node visited having no right child
+    abort      // The subtree root is being updated
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    item atomic.Pointer[dataset.Pair[K, V]] // Element held, nil for the root
+    op *operation[K, V]                     // Operation in progress, flagged with its kind
+    left *node[K, V]
+    right *node[K, V]
+}
+
+// Child CAS ('is_left', 'expected' and 'update') or relocation (the other fields)
+type operation[K cmp.Ordered, V any] struct {
+    is_left bool
+    expected *node[K, V]
+    update *node[K, V]
+    state atomic.Int32               // State of the relocation
+    dest *node[K, V]                 // Node whose element is deleted, then replaced
+    dest_op *operation[K, V]         // Operation field of 'dest' when the relocation started
+    remove_item *dataset.Pair[K, V]  // Element deleted
+    replace_item *dataset.Pair[K, V] // Element relocated
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    root *node[K, V]       // Sentinel, less than every node, all the elements being in its right subtree
+    none *operation[K, V]  // Initial operation of every node
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// Result of a search: the last node visited, its parent, and the operation fields read on these nodes
+type find_record[K cmp.Ordered, V any] struct {
+    pred *node[K, V]
+    pred_op *operation[K, V]
+    curr *node[K, V]
+    curr_op *operation[K, V]
+    curr_item *dataset.Pair[K, V]
+}
+
+// -----------------------------------------------------------------------------
+
+func get_flag[K cmp.Ordered, V any](op *operation[K, V]) uintptr {
+    return uintptr(unsafe.Pointer(op)) & flag_bits
+}
+
+func unflag[K cmp.Ordered, V any](op *operation[K, V]) *operation[K, V] {
+    flag := get_flag(op)
+    if flag == flag_none {
+        return op
+    }
+    return (*operation[K, V])(unsafe.Add(unsafe.Pointer(op), -int(flag))) // Stay in unsafe.Add, so the GC keeps seeing a pointer
+}
+
+func set_flag[K cmp.Ordered, V any](op *operation[K, V], flag uintptr) *operation[K, V] {
+    op = unflag(op)
+    if flag == flag_none {
+        return op
+    }
+    return (*operation[K, V])(unsafe.Add(unsafe.Pointer(op), int(flag)))
+}
+
+func is_null[K cmp.Ordered, V any](n *node[K, V]) bool {
+    return n == nil || uintptr(unsafe.Pointer(n)) & null_bit != 0
+}
+
+func set_null[K cmp.Ordered, V any](n *node[K, V]) *node[K, V] {
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(n), int(null_bit)))
+}
+
+func cas_op[K cmp.Ordered, V any](n *node[K, V], old *operation[K, V], new *operation[K, V]) bool {
+    return atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&n.op)), unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+func cas_child[K cmp.Ordered, V any](field **node[K, V], old *node[K, V], new *node[K, V]) bool {
+    return atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(field)), unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) new_node(key K, val V) *node[K, V] {
+    node := new(node[K, V])
+    node.item.Store(&dataset.Pair[K, V]{Key: key, Val: val})
+    node.op = set.none
+    return node
+}
+
+func new_childcas[K cmp.Ordered, V any](is_left bool, expected *node[K, V], update *node[K, V]) *operation[K, V] {
+    op := new(operation[K, V])
+    op.is_left = is_left
+    op.expected = expected
+    op.update = update
+    return op
+}
+
+func (set *DataSet[K, V]) find(key K, rec *find_record[K, V], aux_root *node[K, V]) int {
+retry:
+    result := notfound_r
+    curr := aux_root
+    curr_op := curr.op
+    var curr_item *dataset.Pair[K, V]
+    if get_flag(curr_op) != flag_none {
+        if aux_root == set.root {
+            help_childcas(unflag(curr_op), curr)
+            goto retry
+        }
+        return abort
+    }
+    next := curr.right
+    last_right := curr
+    last_right_op := curr_op
+    var pred *node[K, V]
+    var pred_op *operation[K, V]
+    for !is_null(next) {
+        pred = curr
+        pred_op = curr_op
+        curr = next
+        curr_op = curr.op
+        if get_flag(curr_op) != flag_none {
+            help(pred, pred_op, curr, curr_op)
+            goto retry
+        }
+        curr_item = curr.item.Load()
+        if key < curr_item.Key {
+            result = notfound_l
+            next = curr.left
+        } else if key > curr_item.Key {
+            result = notfound_r
+            next = curr.right
+            last_right = curr
+            last_right_op = curr_op
+        } else {
+            result = found
+            break
+        }
+    }
+    if result != found && last_right_op != last_right.op {
+        goto retry
+    }
+    if curr.op != curr_op {
+        goto retry
+    }
+    rec.pred = pred
+    rec.pred_op = pred_op
+    rec.curr = curr
+    rec.curr_op = curr_op
+    rec.curr_item = curr_item
+    return result
+}
+
+func help[K cmp.Ordered, V any](pred *node[K, V], pred_op *operation[K, V], curr *node[K, V], curr_op *operation[K, V]) {
+    switch get_flag(curr_op) {
+    case flag_childcas:
+        help_childcas(unflag(curr_op), curr)
+    case flag_relocate:
+        help_relocate(unflag(curr_op), pred, pred_op, curr)
+    case flag_mark:
+        help_marked(pred, pred_op, curr)
+    }
+}
+
+func help_childcas[K cmp.Ordered, V any](op *operation[K, V], dest *node[K, V]) {
+    field := &dest.right
+    if op.is_left {
+        field = &dest.left
+    }
+    cas_child(field, op.expected, op.update)
+    cas_op(dest, set_flag(op, flag_childcas), set_flag(op, flag_none))
+}
+
+func help_marked[K cmp.Ordered, V any](pred *node[K, V], pred_op *operation[K, V], curr *node[K, V]) { // Replace 'curr', which has at most one child, by this child
+    var new_ref *node[K, V]
+    if is_null(curr.left) {
+        if is_null(curr.right) {
+            new_ref = set_null(curr)
+        } else {
+            new_ref = curr.right
+        }
+    } else {
+        new_ref = curr.left
+    }
+    op := new_childcas(curr == pred.left, curr, new_ref)
+    if cas_op(pred, pred_op, set_flag(op, flag_childcas)) {
+        help_childcas(op, pred)
+    }
+}
+
+func help_relocate[K cmp.Ordered, V any](op *operation[K, V], pred *node[K, V], pred_op *operation[K, V], curr *node[K, V]) bool { // Relocate the element of 'curr' into 'op.dest', then remove 'curr'
+    seen_state := op.state.Load()
+    if seen_state == state_ongoing {
+        if cas_op(op.dest, op.dest_op, set_flag(op, flag_relocate)) || op.dest.op == set_flag(op, flag_relocate) {
+            op.state.CompareAndSwap(state_ongoing, state_successful)
+            seen_state = state_successful
+        } else if !op.state.CompareAndSwap(state_ongoing, state_failed) {
+            seen_state = op.state.Load()
+        } else {
+            seen_state = state_failed
+        }
+    }
+    if seen_state == state_successful {
+        op.dest.item.CompareAndSwap(op.remove_item, op.replace_item)
+        cas_op(op.dest, set_flag(op, flag_relocate), set_flag(op, flag_none))
+    }
+    result := seen_state == state_successful
+    if op.dest == curr {
+        return result
+    }
+    flag := flag_none
+    if result {
+        flag = flag_mark
+    }
+    cas_op(curr, set_flag(op, flag_relocate), set_flag(op, flag))
+    if result {
+        if op.dest == pred {
+            pred_op = set_flag(op, flag_none)
+        }
+        help_marked(pred, pred_op, curr)
+    }
+    return result
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    set.none = new(operation[K, V])
+    set.root = new(node[K, V])
+    set.root.op = set.none
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    stack := []*node[K, V]{set.root.right}
+    for len(stack) > 0 {
+        node := stack[len(stack) - 1]
+        stack = stack[:len(stack) - 1]
+        if is_null(node) {
+            continue
+        }
+        if get_flag(node.op) != flag_mark {
+            size++
+        }
+        stack = append(stack, node.right, node.left)
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    var rec find_record[K, V]
+    if set.find(key, &rec, set.root) == found {
+        return rec.curr_item.Val, true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var rec find_record[K, V]
+    for {
+        result := set.find(key, &rec, set.root)
+        if result == found {
+            return false
+        }
+        curr := rec.curr
+        is_left := result == notfound_l
+        old := curr.right
+        if is_left {
+            old = curr.left
+        }
+        op := new_childcas(is_left, old, set.new_node(key, val))
+        if cas_op(curr, rec.curr_op, set_flag(op, flag_childcas)) {
+            help_childcas(op, curr)
+            set.count.Add(1)
+            return true
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    var rec, repl find_record[K, V]
+    for {
+        if set.find(key, &rec, set.root) != found {
+            var zero V
+            return zero, false
+        }
+        curr := rec.curr
+        if is_null(curr.right) || is_null(curr.left) { // At most one child: mark and remove the node
+            if cas_op(curr, rec.curr_op, set_flag(rec.curr_op, flag_mark)) {
+                help_marked(rec.pred, rec.pred_op, curr)
+                set.count.Add(-1)
+                return rec.curr_item.Val, true
+            }
+            continue
+        }
+        if set.find(key, &repl, curr) == abort || curr.op != rec.curr_op { // Two children: relocate the successor, the smallest node of the right subtree
+            continue
+        }
+        op := new(operation[K, V])
+        op.dest = curr
+        op.dest_op = rec.curr_op
+        op.remove_item = rec.curr_item
+        op.replace_item = repl.curr_item
+        if cas_op(repl.curr, repl.curr_op, set_flag(op, flag_relocate)) && help_relocate(op, repl.pred, repl.pred_op, repl.curr) {
+            set.count.Add(-1)
+            return rec.curr_item.Val, true
+        }
+    }
+}
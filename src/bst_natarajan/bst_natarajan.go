@@ -0,0 +1,313 @@
+/**
+ * @file   bst_natarajan.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Fast Concurrent Lock-Free Binary Search Trees,
+ * Aravind Natarajan and Neeraj Mittal,
+ * PPoPP 2014.
+ *
+ * External tree: the elements are stored in the leaves, the internal nodes only
+ * route the searches. The edges are marked instead of the nodes: the edge to a
+ * leaf being deleted is flagged, the edge to its sibling is tagged, then both
+ * the parent and the leaf get removed by a single CAS on the edge leading to
+ * the parent. The three infinite keys of the paper are sentinel nodes, greater
+ * than every key, and never compared with each other.
+**/
+
+package bst_natarajan
+
+import (
+    "cmp"
+    "sync/atomic"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    flag_bit = uintptr(1) // The leaf at the end of the edge is being deleted
+    tag_bit  = uintptr(2) // The parent at the origin of the edge is being deleted, the edge must not change
+    edge_bits = flag_bit | tag_bit
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    bound share.Bound // BOUND_MAX for the sentinels, BOUND_NONE otherwise
+    left *node[K, V]  // Children, both nil for a leaf, both non-nil otherwise (edges carry the flag and tag bits)
+    right *node[K, V]
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    root *node[K, V]       // Sentinel 'R' of the paper, whose left child is the sentinel 'S'
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// Result of a seek: the last edge not tagged (ancestor -> successor) on the access path, and the last edge (parent -> leaf)
+type seek_record[K cmp.Ordered, V any] struct {
+    ancestor *node[K, V]
+    successor *node[K, V]
+    parent *node[K, V]
+    leaf *node[K, V]
+}
+
+// -----------------------------------------------------------------------------
+
+func get_bits[K cmp.Ordered, V any](i *node[K, V]) uintptr {
+    return uintptr(unsafe.Pointer(i)) & edge_bits
+}
+
+func is_flagged[K cmp.Ordered, V any](i *node[K, V]) bool {
+    return get_bits(i) & flag_bit != 0
+}
+
+func is_tagged[K cmp.Ordered, V any](i *node[K, V]) bool {
+    return get_bits(i) & tag_bit != 0
+}
+
+func get_addr[K cmp.Ordered, V any](i *node[K, V]) *node[K, V] {
+    bits := get_bits(i)
+    if bits == 0 {
+        return i
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(i), -int(bits))) // Stay in unsafe.Add, so the GC keeps seeing a pointer
+}
+
+func with_bits[K cmp.Ordered, V any](i *node[K, V], bits uintptr) *node[K, V] { // 'i' must be unmarked
+    if bits == 0 {
+        return i
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(i), int(bits)))
+}
+
+func cas_edge[K cmp.Ordered, V any](edge **node[K, V], old *node[K, V], new *node[K, V]) bool {
+    return atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(edge)), unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+func tag_edge[K cmp.Ordered, V any](edge **node[K, V]) { // Set the tag bit of the edge (bit-test-and-set of the paper)
+    for {
+        old := *edge
+        if is_tagged(old) || cas_edge(edge, old, with_bits(get_addr(old), get_bits(old) | tag_bit)) {
+            return
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) greater(key K) bool { // Whether the search for 'key' goes left of 'n'
+    return n.bound == share.BOUND_MAX || key < n.key
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) is_leaf() bool {
+    return get_addr(n.left) == nil
+}
+
+func (n *node[K, V]) child(key K) **node[K, V] { // Edge followed by the search for 'key'
+    if n.greater(key) {
+        return &n.left
+    }
+    return &n.right
+}
+
+func (n *node[K, V]) sibling(key K) **node[K, V] { // Edge not followed by the search for 'key'
+    if n.greater(key) {
+        return &n.right
+    }
+    return &n.left
+}
+
+func new_leaf[K cmp.Ordered, V any](key K, val V) *node[K, V] {
+    node := new(node[K, V])
+    node.key = key
+    node.val = val
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](left *node[K, V], right *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.bound = share.BOUND_MAX
+    node.left = left
+    node.right = right
+    return node
+}
+
+func new_internal[K cmp.Ordered, V any](leaf *node[K, V], key K, val V) *node[K, V] { // Replacement of 'leaf', routing to both 'leaf' and a new leaf of 'key'
+    node := new(node[K, V])
+    add := new_leaf(key, val)
+    if leaf.greater(key) {
+        node.key = leaf.key
+        node.bound = leaf.bound
+        node.left = add
+        node.right = leaf
+    } else {
+        node.key = key
+        node.left = leaf
+        node.right = add
+    }
+    return node
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) seek(key K, rec *seek_record[K, V]) {
+    rec.ancestor = set.root
+    rec.successor = set.root.left
+    rec.parent = rec.successor
+    rec.leaf = get_addr(rec.parent.left)
+    parent_field := rec.parent.left
+    current_field := rec.leaf.left
+    current := get_addr(current_field)
+    for current != nil {
+        if !is_tagged(parent_field) { // Move the ancestor and successor one level down
+            rec.ancestor = rec.parent
+            rec.successor = rec.leaf
+        }
+        rec.parent = rec.leaf
+        rec.leaf = current
+        parent_field = current_field
+        current_field = *current.child(key)
+        current = get_addr(current_field)
+    }
+}
+
+func (set *DataSet[K, V]) cleanup(key K, rec *seek_record[K, V]) bool { // Remove the parent and its flagged leaf, return whether the removal was done by this call
+    successor_addr := rec.ancestor.child(key)
+    child_addr := rec.parent.child(key)
+    sibling_addr := rec.parent.sibling(key)
+    if !is_flagged(*child_addr) { // The leaf being deleted is the sibling, so the child is kept
+        sibling_addr = child_addr
+    }
+    tag_edge(sibling_addr)
+    sibling := *sibling_addr
+    return cas_edge(successor_addr, rec.successor, with_bits(get_addr(sibling), get_bits(sibling) & flag_bit)) // Keep the flag, if any
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    var key K
+    var val V
+    inf0 := new_leaf(key, val)
+    inf0.bound = share.BOUND_MAX
+    inf1 := new_leaf(key, val)
+    inf1.bound = share.BOUND_MAX
+    inf2 := new_leaf(key, val)
+    inf2.bound = share.BOUND_MAX
+    set.root = new_sentinel(new_sentinel(inf0, inf1), inf2)
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    stack := []*node[K, V]{set.root.left} // Edges to visit, a flagged edge leading to a leaf being deleted
+    for len(stack) > 0 {
+        edge := stack[len(stack) - 1]
+        stack = stack[:len(stack) - 1]
+        node := get_addr(edge)
+        if node.is_leaf() {
+            if node.bound == share.BOUND_NONE && !is_flagged(edge) {
+                size++
+            }
+            continue
+        }
+        stack = append(stack, node.right, node.left)
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    var rec seek_record[K, V]
+    set.seek(key, &rec)
+    if rec.leaf.equal(key) {
+        return rec.leaf.val, true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var rec seek_record[K, V]
+    for {
+        set.seek(key, &rec)
+        leaf := rec.leaf
+        if leaf.equal(key) {
+            return false
+        }
+        child_addr := rec.parent.child(key)
+        if cas_edge(child_addr, leaf, new_internal(leaf, key, val)) {
+            set.count.Add(1)
+            return true
+        }
+        child := *child_addr
+        if get_addr(child) == leaf && get_bits(child) != 0 { // The leaf or its sibling is being deleted, help before retrying
+            set.cleanup(key, &rec)
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    var rec seek_record[K, V]
+    var leaf *node[K, V] = nil // Leaf flagged by this call, nil while injecting
+    for {
+        set.seek(key, &rec)
+        if leaf == nil { // Injection: flag the edge to the leaf
+            if !rec.leaf.equal(key) {
+                var zero V
+                return zero, false
+            }
+            child_addr := rec.parent.child(key)
+            if cas_edge(child_addr, rec.leaf, with_bits(rec.leaf, flag_bit)) {
+                leaf = rec.leaf
+                set.count.Add(-1)
+                if set.cleanup(key, &rec) {
+                    return leaf.val, true
+                }
+                continue
+            }
+            child := *child_addr
+            if get_addr(child) == rec.leaf && get_bits(child) != 0 { // Being deleted, help before retrying
+                set.cleanup(key, &rec)
+            }
+        } else { // Cleanup: remove the flagged leaf, unless another thread did
+            if rec.leaf != leaf || set.cleanup(key, &rec) {
+                return leaf.val, true
+            }
+        }
+    }
+}
@@ -0,0 +1,212 @@
+/**
+ * @file   bst_optik.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * External binary search tree synchronized with OPTIK locks, following
+ * Optimistic Concurrency with OPTIK,
+ * Rachid Guerraoui, Vasileios Trigonakis,
+ * PPoPP 2016.
+ *
+ * Same design as BST-TK, with a single OPTIK lock per internal node: the updates
+ * read the version of the parent (and grandparent) before following its child,
+ * then only lock it if this version did not change meanwhile. A deleted internal
+ * node gets the deleted version, so that no update ever locks it.
+**/
+
+package bst_optik
+
+import (
+    "cmp"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/optik"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    bound share.Bound    // BOUND_MAX for the sentinels, BOUND_NONE otherwise
+    child [2]*node[K, V] // Left and right children, both nil for a leaf
+    lock optik.Mutex     // Lock of both children, internal nodes only
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    root *node[K, V]       // Internal sentinel, the parent of the leftmost leaf (another sentinel)
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) side(key K) int { // Index of the child followed by the search for 'key'
+    if n.bound == share.BOUND_MAX || key < n.key {
+        return 0
+    }
+    return 1
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) is_leaf() bool {
+    return n.child[0] == nil
+}
+
+func new_leaf[K cmp.Ordered, V any](key K, val V) *node[K, V] {
+    node := new(node[K, V])
+    node.key = key
+    node.val = val
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](left *node[K, V], right *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.bound = share.BOUND_MAX
+    node.child[0] = left
+    node.child[1] = right
+    return node
+}
+
+func new_internal[K cmp.Ordered, V any](leaf *node[K, V], key K, val V) *node[K, V] { // Replacement of 'leaf', routing to both 'leaf' and a new leaf of 'key'
+    node := new(node[K, V])
+    add := new_leaf(key, val)
+    if leaf.side(key) == 0 {
+        node.key = leaf.key
+        node.bound = leaf.bound
+        node.child[0] = add
+        node.child[1] = leaf
+    } else {
+        node.key = key
+        node.child[0] = leaf
+        node.child[1] = add
+    }
+    return node
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    var key K
+    var val V
+    min := new_leaf(key, val)
+    min.bound = share.BOUND_MAX
+    max := new_leaf(key, val)
+    max.bound = share.BOUND_MAX
+    set.root = new_sentinel(min, max)
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    stack := []*node[K, V]{set.root}
+    for len(stack) > 0 {
+        node := stack[len(stack) - 1]
+        stack = stack[:len(stack) - 1]
+        if node.is_leaf() {
+            if node.bound == share.BOUND_NONE {
+                size++
+            }
+            continue
+        }
+        stack = append(stack, node.child[1], node.child[0])
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    curr := set.root
+    for !curr.is_leaf() {
+        curr = curr.child[curr.side(key)]
+    }
+    if curr.equal(key) {
+        return curr.val, true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    for {
+        var pred *node[K, V]
+        var pred_side int
+        var predv optik.Mutex
+        curr := set.root
+        for !curr.is_leaf() {
+            pred, pred_side = curr, curr.side(key)
+            predv = pred.lock.Load()
+            curr = pred.child[pred_side]
+        }
+        if curr.equal(key) {
+            return false
+        }
+        add := new_internal(curr, key, val)
+        if !pred.lock.TryLock_version(predv) { // Also if deleted
+            continue
+        }
+        pred.child[pred_side] = add
+        pred.lock.Unlock()
+        set.count.Add(1)
+        return true
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    for {
+        var ppred, pred *node[K, V]
+        var ppred_side, pred_side int
+        var ppredv, predv optik.Mutex
+        curr := set.root
+        for !curr.is_leaf() {
+            ppred, ppred_side, ppredv = pred, pred_side, predv
+            pred, pred_side = curr, curr.side(key)
+            predv = pred.lock.Load()
+            curr = pred.child[pred_side]
+        }
+        if !curr.equal(key) { // A real leaf always has a grandparent, the root being the parent of a sentinel
+            var zero V
+            return zero, false
+        }
+        if !ppred.lock.TryLock_version(ppredv) {
+            continue
+        }
+        if !pred.lock.TryLock_vdelete(predv) {
+            ppred.lock.Revert()
+            continue
+        }
+        ppred.child[ppred_side] = pred.child[1 - pred_side]
+        ppred.lock.Unlock()
+        set.count.Add(-1)
+        return curr.val, true
+    }
+}
@@ -0,0 +1,240 @@
+/**
+ * @file   bst_tk.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * BST-TK: external binary search tree, from
+ * Asynchronized Concurrency: The Secret to Scaling Concurrent Search Data Structures,
+ * Tudor David, Rachid Guerraoui, Vasileios Trigonakis,
+ * ASPLOS 2015.
+ *
+ * Each internal node holds one ticket lock per child, whose version (the amount
+ * of releases) is read before following the child: the updates only try to lock
+ * with the version they saw, restarting from the root if the child changed.
+ * A deleted internal node is never unlocked, so that no update ever locks it.
+**/
+
+package bst_tk
+
+import (
+    "cmp"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+// Ticket lock with version: version in the high 16 bits, ticket in the low 16 bits, free when both are equal
+type tlock = atomic.Uint32
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    bound share.Bound    // BOUND_MAX for the sentinels, BOUND_NONE otherwise
+    child [2]*node[K, V] // Left and right children, both nil for a leaf
+    lock [2]tlock        // Lock of each child, internal nodes only
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    root *node[K, V]       // Internal sentinel, the parent of the leftmost leaf (another sentinel)
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func is_free(v uint32) bool {
+    return v >> 16 == v & 0xffff
+}
+
+func try_lock_version(lock *tlock, v uint32) bool { // Take a ticket only if the lock is still in version 'v', and free
+    return is_free(v) && lock.CompareAndSwap(v, v & 0xffff0000 | (v + 1) & 0xffff)
+}
+
+func unlock(lock *tlock) {
+    lock.Add(1 << 16) // Overflows out of the word, the ticket is not affected
+}
+
+func revert(lock *tlock, v uint32) { // Release without changing the version, 'v' being the version locked with
+    lock.Store(v)
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) side(key K) int { // Index of the child followed by the search for 'key'
+    if n.bound == share.BOUND_MAX || key < n.key {
+        return 0
+    }
+    return 1
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) is_leaf() bool {
+    return n.child[0] == nil
+}
+
+func new_leaf[K cmp.Ordered, V any](key K, val V) *node[K, V] {
+    node := new(node[K, V])
+    node.key = key
+    node.val = val
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](left *node[K, V], right *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.bound = share.BOUND_MAX
+    node.child[0] = left
+    node.child[1] = right
+    return node
+}
+
+func new_internal[K cmp.Ordered, V any](leaf *node[K, V], key K, val V) *node[K, V] { // Replacement of 'leaf', routing to both 'leaf' and a new leaf of 'key'
+    node := new(node[K, V])
+    add := new_leaf(key, val)
+    if leaf.side(key) == 0 {
+        node.key = leaf.key
+        node.bound = leaf.bound
+        node.child[0] = add
+        node.child[1] = leaf
+    } else {
+        node.key = key
+        node.child[0] = leaf
+        node.child[1] = add
+    }
+    return node
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    var key K
+    var val V
+    min := new_leaf(key, val)
+    min.bound = share.BOUND_MAX
+    max := new_leaf(key, val)
+    max.bound = share.BOUND_MAX
+    set.root = new_sentinel(min, max)
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    stack := []*node[K, V]{set.root}
+    for len(stack) > 0 {
+        node := stack[len(stack) - 1]
+        stack = stack[:len(stack) - 1]
+        if node.is_leaf() {
+            if node.bound == share.BOUND_NONE {
+                size++
+            }
+            continue
+        }
+        stack = append(stack, node.child[1], node.child[0])
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    curr := set.root
+    for !curr.is_leaf() {
+        curr = curr.child[curr.side(key)]
+    }
+    if curr.equal(key) {
+        return curr.val, true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    for {
+        var pred *node[K, V]
+        var pred_side int
+        var predv uint32
+        curr := set.root
+        for !curr.is_leaf() {
+            pred, pred_side = curr, curr.side(key)
+            predv = pred.lock[pred_side].Load()
+            curr = pred.child[pred_side]
+        }
+        if curr.equal(key) {
+            return false
+        }
+        add := new_internal(curr, key, val)
+        if !try_lock_version(&pred.lock[pred_side], predv) {
+            continue
+        }
+        pred.child[pred_side] = add
+        unlock(&pred.lock[pred_side])
+        set.count.Add(1)
+        return true
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    for {
+        var ppred, pred *node[K, V]
+        var ppred_side, pred_side int
+        var ppredv uint32
+        var predv [2]uint32
+        curr := set.root
+        for !curr.is_leaf() {
+            ppred, ppred_side, ppredv = pred, pred_side, predv[pred_side]
+            pred, pred_side = curr, curr.side(key)
+            predv[0] = pred.lock[0].Load()
+            predv[1] = pred.lock[1].Load()
+            curr = pred.child[pred_side]
+        }
+        if !curr.equal(key) { // A real leaf always has a grandparent, the root being the parent of a sentinel
+            var zero V
+            return zero, false
+        }
+        if !try_lock_version(&ppred.lock[ppred_side], ppredv) {
+            continue
+        }
+        if !try_lock_version(&pred.lock[0], predv[0]) {
+            revert(&ppred.lock[ppred_side], ppredv)
+            continue
+        }
+        if !try_lock_version(&pred.lock[1], predv[1]) {
+            revert(&pred.lock[0], predv[0])
+            revert(&ppred.lock[ppred_side], ppredv)
+            continue
+        }
+        ppred.child[ppred_side] = pred.child[1 - pred_side] // 'pred' stays locked forever
+        unlock(&ppred.lock[ppred_side])
+        set.count.Add(-1)
+        return curr.val, true
+    }
+}
@@ -1 +0,0 @@
-../linkedlist_optik.go
\ No newline at end of file
@@ -0,0 +1,170 @@
+/**
+ * @file   dataset.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Interfaces shared by the data structures, one per kind of data structure.
+**/
+
+package dataset
+
+import (
+    "iter"
+)
+
+// -----------------------------------------------------------------------------
+
+// Kind of data structure, telling which interface is implemented
+type Kind uint
+
+const (
+    KIND_SET Kind = iota
+    KIND_QUEUE
+    KIND_STACK
+    KIND_PRIORITY_QUEUE
+    KIND_DEQUE
+)
+
+func (kind Kind) String() string {
+    switch kind {
+    case KIND_SET:
+        return "set"
+    case KIND_QUEUE:
+        return "queue"
+    case KIND_STACK:
+        return "stack"
+    case KIND_PRIORITY_QUEUE:
+        return "priority queue"
+    case KIND_DEQUE:
+        return "deque"
+    default:
+        return "unknown"
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+// Operations implemented by every data structure
+type Container interface {
+    Destroy()
+    Size() uint
+}
+
+// Searchable data structure: every operation acts on the given key
+type Set[K any, V any] interface {
+    Container
+    Find(key K) (V, bool)
+    Insert(key K, val V) bool
+    Delete(key K) (V, bool)
+}
+
+// Element of a set, as copied by 'Snapshot' or loaded by 'BulkLoad'
+type Pair[K any, V any] struct {
+    Key K
+    Val V
+}
+
+// Set able to copy its elements at one point in time
+type SnapshotSet[K any, V any] interface {
+    Set[K, V]
+    Snapshot() []Pair[K, V] // Elements present at one point in time during the call (by increasing key for the ordered sets)
+}
+
+// Set able to load many elements at once, before being shared
+type BulkSet[K any, V any] interface {
+    Set[K, V]
+    BulkLoad(pairs []Pair[K, V]) error // Fill the empty set in one pass, not thread-safe (the data structures keeping their keys sorted require strictly increasing keys, the others keep the first of repeated keys)
+}
+
+// Set amortizing its synchronization over several keys, each key (but not the whole batch) being inserted or deleted atomically
+type BatchSet[K any, V any] interface {
+    Set[K, V]
+    InsertBatch(pairs []Pair[K, V]) uint // Insert the elements whose key is absent, returning the amount inserted
+    DeleteBatch(keys []K) []Pair[K, V]   // Delete the present keys, returning the elements deleted
+}
+
+// Set whose values can be replaced atomically
+type Map[K any, V any] interface {
+    Set[K, V]
+    Put(key K, val V) (V, bool)                                 // Insert or replace, returning the previous value if any
+    CompareAndSwap(key K, old V, new V) bool                    // Replace the value only if present and equal to 'old' (panics if not comparable)
+    LoadOrStore(key K, val V) (V, bool)                         // Get the present value (true), or insert 'val' (false)
+    Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) // Replace the current value (ok) or absence (!ok) by the result of 'fn' (absence if false), returning the new state; 'fn' may be called more than once
+}
+
+// Data structure keeping its keys sorted, supporting navigation queries (each returning the key and value found, and whether one was found)
+type Navigable[K any, V any] interface {
+    Min() (K, V, bool)              // Element of the smallest key
+    Max() (K, V, bool)              // Element of the largest key
+    Ceiling(key K) (K, V, bool)     // Element of the smallest key not less than 'key'
+    Floor(key K) (K, V, bool)       // Element of the largest key not greater than 'key'
+    Successor(key K) (K, V, bool)   // Element of the smallest key greater than 'key'
+    Predecessor(key K) (K, V, bool) // Element of the largest key less than 'key'
+}
+
+// Set keeping its keys sorted, the scans being weakly consistent: an element present (resp. absent) during the whole scan is (resp. is not) visited, an element inserted or deleted concurrently may be visited or not
+type OrderedSet[K any, V any] interface {
+    Set[K, V]
+    Navigable[K, V]
+    Range(lo K, hi K, fn func(key K, val V) bool) // Call 'fn' on the elements in [lo, hi] by increasing key, until it returns false
+    All() iter.Seq2[K, V]                         // Iterate over every element by increasing key
+}
+
+// Ordered set also offering atomic range scans, i.e. visiting a snapshot of the range taken at one point in time
+type AtomicOrderedSet[K any, V any] interface {
+    OrderedSet[K, V]
+    RangeAtomic(lo K, hi K, fn func(key K, val V) bool) // Same as 'Range', but atomic
+}
+
+// FIFO queue
+type Queue[V any] interface {
+    Container
+    Enqueue(val V)
+    Dequeue() (V, bool) // Remove the oldest element
+    Peek() (V, bool)    // Get the oldest element, without removing it
+}
+
+// LIFO stack
+type Stack[V any] interface {
+    Container
+    Push(val V)
+    Pop() (V, bool)     // Remove the newest element
+    Peek() (V, bool)    // Get the newest element, without removing it
+}
+
+// Priority queue, the smallest key having the highest priority
+type PriorityQueue[K any, V any] interface {
+    Container
+    InsertWithPriority(key K, val V) bool // Fail if the priority is already present
+    DeleteMin() (K, V, bool)              // Remove the element of highest priority
+    Peek() (K, V, bool)                   // Get the element of highest priority, without removing it
+}
+
+// Relaxed priority queue, whose DeleteMin removes one of the elements of highest priority instead of the highest one
+type RelaxedPriorityQueue[K any, V any] interface {
+    PriorityQueue[K, V]
+    Relaxation() uint // Relaxation actually used, 1 if the order is exact
+}
+
+// Work-stealing deque, owned by one thread: only the owner pushes and pops at the bottom, any thread steals at the top
+type Deque[V any] interface {
+    Container
+    PushBottom(val V)     // Owner only
+    PopBottom() (V, bool) // Owner only: remove the newest element
+    Steal() (V, bool)     // Remove the oldest element
+}
@@ -0,0 +1,153 @@
+/**
+ * @file   deque_chaselev.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Chase and Lev's dynamic circular work-stealing deque.
+ * The owner pushes and pops at the bottom without any atomic read-modify-write
+ * but when taking the last element, the thieves steal from the top with a CAS.
+ * The circular buffer doubles when full: the old one is left to the thieves
+ * still reading it, its elements (below the bottom) being never overwritten.
+**/
+
+package deque_chaselev
+
+import (
+    "runtime"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    cache_line_size = 64
+)
+
+// -----------------------------------------------------------------------------
+
+type buffer[V any] struct {
+    mask int64
+    slots []atomic.Pointer[V] // Boxed values, so that a thief reads a slot atomically
+}
+
+type DataSet[V any] struct {
+    top atomic.Int64 // Index of the oldest element, only increased
+    _ [cache_line_size - 8]byte
+    bottom atomic.Int64 // Index of the next element pushed, only written by the owner
+    _ [cache_line_size - 8]byte
+    buf atomic.Pointer[buffer[V]]
+}
+
+// -----------------------------------------------------------------------------
+
+func new_buffer[V any](size int64) *buffer[V] {
+    buf := new(buffer[V])
+    buf.mask = size - 1
+    buf.slots = make([]atomic.Pointer[V], size)
+    return buf
+}
+
+func (buf *buffer[V]) get(i int64) *V {
+    return buf.slots[i & buf.mask].Load()
+}
+
+func (buf *buffer[V]) put(i int64, val *V) {
+    buf.slots[i & buf.mask].Store(val)
+}
+
+func (buf *buffer[V]) grow(bottom int64, top int64) *buffer[V] { // Copy of the elements in [top, bottom), in a buffer twice as large
+    res := new_buffer[V](2 * (buf.mask + 1))
+    for i := top; i < bottom; i++ {
+        res.put(i, buf.get(i))
+    }
+    return res
+}
+
+// -----------------------------------------------------------------------------
+
+func New[V any](opts share.Options) (*DataSet[V], error) { // Uses Capacity (initial size of the buffer, power of 2)
+    capacity := share.Or_default(opts.Capacity, share.DEFAULT_CAPACITY)
+    if !share.Is_pow2(capacity) {
+        return nil, share.Invalid_option("deque_chaselev", "capacity", capacity, "a power of 2")
+    }
+    set := new(DataSet[V])
+    set.buf.Store(new_buffer[V](int64(capacity)))
+    return set, nil
+}
+
+func (set *DataSet[V]) Destroy() {
+}
+
+func (set *DataSet[V]) Size() uint {
+    top := set.top.Load()
+    bottom := set.bottom.Load()
+    if bottom <= top { // Empty, or the owner is taking the last element
+        return 0
+    }
+    return uint(bottom - top)
+}
+
+func (set *DataSet[V]) PushBottom(val V) {
+    bottom := set.bottom.Load()
+    top := set.top.Load()
+    buf := set.buf.Load()
+    if bottom - top > buf.mask { // Full
+        buf = buf.grow(bottom, top)
+        set.buf.Store(buf)
+    }
+    buf.put(bottom, &val)
+    set.bottom.Store(bottom + 1) // Publishes the element to the thieves
+}
+
+func (set *DataSet[V]) PopBottom() (V, bool) {
+    var zero V
+    bottom := set.bottom.Load() - 1
+    buf := set.buf.Load()
+    set.bottom.Store(bottom) // Sequentially consistent, hence ordered before the read of 'top'
+    top := set.top.Load()
+    if bottom < top { // Empty
+        set.bottom.Store(top)
+        return zero, false
+    }
+    val := buf.get(bottom)
+    if bottom > top { // Not the last element, which no thief can reach
+        return *val, true
+    }
+    won := set.top.CompareAndSwap(top, top + 1) // Race with the thieves for the last element
+    set.bottom.Store(top + 1)
+    if !won {
+        return zero, false
+    }
+    return *val, true
+}
+
+func (set *DataSet[V]) Steal() (V, bool) {
+    for {
+        top := set.top.Load()
+        bottom := set.bottom.Load()
+        if top >= bottom {
+            var zero V
+            return zero, false
+        }
+        val := set.buf.Load().get(top)
+        if set.top.CompareAndSwap(top, top + 1) {
+            return *val, true
+        }
+        runtime.Gosched() // Lost to the owner or another thief
+    }
+}
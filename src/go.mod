@@ -0,0 +1,3 @@
+module github.com/LPD-EPFL/ASCYLIB-Go/src
+
+go 1.24
@@ -0,0 +1,365 @@
+/**
+ * @file   hashtable_clht_lb.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * CLHT-LB: lock-based cache-line hash table, with resizing, from
+ * Asynchronized Concurrency: The Secret to Scaling Concurrent Search Data Structures,
+ * Tudor David, Rachid Guerraoui, Vasileios Trigonakis,
+ * ASPLOS 2015.
+ *
+ * Each bucket holds a few elements in place, and gets chained to overflow
+ * buckets once full. The updates lock the first bucket of the chain, and modify
+ * the elements in place. As a generic key or value may span several words, the
+ * searches copy each bucket then validate the version of the lock, instead of
+ * re-reading the key as in C. Once too many overflow buckets were allocated, the
+ * table is doubled: every chain gets frozen (its lock marked as deleted, so that
+ * no update succeeds) then copied, before the new table is published.
+**/
+
+package hashtable_clht_lb
+
+import (
+    "math/bits"
+    "runtime"
+    "sync"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/optik"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    entries_per_bucket = 3 // With 8-byte keys and values, a bucket fills a 64-byte cache line
+    perc_expansions = 5    // Percentage of overflow buckets (over the amount of buckets) triggering a resize
+)
+
+// -----------------------------------------------------------------------------
+
+type slots[K comparable, V any] struct {
+    used uint8 // Bit i set if the slot i holds an element
+    key [entries_per_bucket]K
+    val [entries_per_bucket]V
+}
+
+type bucket[K comparable, V any] struct {
+    lock optik.Mutex // Lock of the chain, first bucket only (deleted once the chain is frozen)
+    slots[K, V]
+    next *bucket[K, V] // Overflow bucket
+}
+
+type table[K comparable, V any] struct {
+    hash uint
+    buckets []bucket[K, V]
+    expansions atomic.Int64 // Amount of overflow buckets
+    threshold int64         // Amount of overflow buckets triggering a resize
+}
+
+type DataSet[K comparable, V any] struct {
+    hasher share.Hasher[K]
+    table atomic.Pointer[table[K, V]] // Current table
+    resize sync.Mutex                 // Held while resizing
+    count *counter.Striped            // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func new_table[K comparable, V any](num_buckets uint) *table[K, V] {
+    t := new(table[K, V])
+    t.hash = num_buckets - 1
+    t.buckets = make([]bucket[K, V], num_buckets)
+    t.threshold = max(1, int64(num_buckets) * perc_expansions / 100)
+    return t
+}
+
+func (s *slots[K, V]) index(key K) int { // Slot holding 'key', -1 if none
+    for j := 0; j < entries_per_bucket; j++ {
+        if s.used & (1 << j) != 0 && s.key[j] == key {
+            return j
+        }
+    }
+    return -1
+}
+
+func (head *bucket[K, V]) search(key K) (*bucket[K, V], int) { // Bucket and slot holding 'key' in the chain, nil if none
+    for b := head; b != nil; b = b.next {
+        if j := b.index(key); j >= 0 {
+            return b, j
+        }
+    }
+    return nil, -1
+}
+
+func (t *table[K, V]) put(head *bucket[K, V], key K, val V) { // Store in the first free slot of the chain, which must not hold 'key'
+    b := head
+    for {
+        for j := 0; j < entries_per_bucket; j++ {
+            if b.used & (1 << j) == 0 {
+                b.key[j] = key
+                b.val[j] = val
+                b.used |= 1 << j
+                return
+            }
+        }
+        if b.next == nil {
+            break
+        }
+        b = b.next
+    }
+    next := new(bucket[K, V])
+    next.key[0] = key
+    next.val[0] = val
+    next.used = 1
+    b.next = next
+    t.expansions.Add(1)
+}
+
+func (b *bucket[K, V]) clear(j int) {
+    var key K
+    var val V
+    b.key[j] = key // Do not retain the element
+    b.val[j] = val
+    b.used &^= 1 << j
+}
+
+func (set *DataSet[K, V]) lock_bucket(key K) (*table[K, V], *bucket[K, V]) { // Lock the chain of 'key' in the current table
+    for {
+        t := set.table.Load()
+        head := &t.buckets[set.hasher(key) & t.hash]
+        ver := head.lock.Load()
+        if !optik.Is_locked(ver) && head.lock.TryLock_version(ver) {
+            return t, head
+        }
+        runtime.Gosched() // Locked by another update, or frozen until the resized table gets published
+    }
+}
+
+func (set *DataSet[K, V]) expand(t *table[K, V]) { // Double the table 't', unless already being resized
+    if !set.resize.TryLock() {
+        return
+    }
+    defer set.resize.Unlock()
+    if set.table.Load() != t {
+        return
+    }
+    next := new_table[K, V](2 * uint(len(t.buckets)))
+    for i := range t.buckets {
+        head := &t.buckets[i]
+        for !head.lock.TryLock_vdelete(head.lock.Get_version_wait()) { // Wait for the update in progress, if any, then freeze the chain
+        }
+        for b := head; b != nil; b = b.next {
+            for j := 0; j < entries_per_bucket; j++ {
+                if b.used & (1 << j) != 0 {
+                    next.put(&next.buckets[set.hasher(b.key[j]) & next.hash], b.key[j], b.val[j])
+                }
+            }
+        }
+    }
+    set.table.Store(next)
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    t, head := set.lock_bucket(key)
+    b, j := head.search(key)
+    found := b != nil
+    var cur V
+    if found {
+        cur = b.val[j]
+    }
+
+    val, action := fn(cur, found)
+    switch {
+    case action == share.ACTION_STORE:
+        expansions := t.expansions.Load()
+        if found {
+            b.val[j] = val
+        } else {
+            t.put(head, key, val)
+        }
+        head.lock.Unlock()
+        if !found {
+            set.count.Add(1)
+            if t.expansions.Load() > expansions && t.expansions.Load() >= t.threshold {
+                set.expand(t)
+            }
+        }
+        return val, true
+    case action == share.ACTION_DELETE && found:
+        b.clear(j)
+        head.lock.Unlock()
+        set.count.Add(-1)
+        var zero V
+        return zero, false
+    default:
+        head.lock.Revert() // Unchanged, so the searches need not retry
+        return cur, found
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses NumBuckets (initial amount) and AtomicSize
+    num_buckets := share.Or_default(opts.NumBuckets, share.DEFAULT_NUM_BUCKETS)
+    if !share.Is_pow2(num_buckets) {
+        return nil, share.Invalid_option("hashtable_clht_lb", "amount of buckets", num_buckets, "a power of 2")
+    }
+    set := new(DataSet[K, V])
+    set.hasher = share.NewHasher[K]()
+    set.table.Store(new_table[K, V](num_buckets))
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    t := set.table.Load()
+    for i := range t.buckets {
+        for b := &t.buckets[i]; b != nil; b = b.next {
+            size += uint(bits.OnesCount8(b.used))
+        }
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+retry:
+    t := set.table.Load()
+    head := &t.buckets[set.hasher(key) & t.hash]
+    ver := head.lock.Load()
+    if optik.Is_locked(ver) && !optik.Is_deleted(ver) { // A frozen chain can be read, as it does not change anymore
+        runtime.Gosched()
+        goto retry
+    }
+    for b := head; b != nil; {
+        copy := b.slots
+        next := b.next
+        if !optik.Is_same_version(ver, head.lock.Load()) { // The copy may be inconsistent
+            goto retry
+        }
+        if j := copy.index(key); j >= 0 {
+            return copy.val[j], true
+        }
+        b = next
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    t, head := set.lock_bucket(key)
+    if b, _ := head.search(key); b != nil {
+        head.lock.Revert()
+        return false
+    }
+    expansions := t.expansions.Load()
+    t.put(head, key, val)
+    head.lock.Unlock()
+    set.count.Add(1)
+    if t.expansions.Load() > expansions && t.expansions.Load() >= t.threshold { // This insertion allocated the overflow bucket reaching the threshold
+        set.expand(t)
+    }
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    _, head := set.lock_bucket(key)
+    b, j := head.search(key)
+    if b == nil {
+        head.lock.Revert()
+        var zero V
+        return zero, false
+    }
+    val := b.val[j]
+    b.clear(j)
+    head.lock.Unlock()
+    set.count.Add(-1)
+    return val, true
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Lock every chain in order, holding off the resizes
+    set.resize.Lock()
+    defer set.resize.Unlock()
+    t := set.table.Load()
+    for i := range t.buckets {
+        t.buckets[i].lock.Lock()
+    }
+    var res []dataset.Pair[K, V]
+    for i := range t.buckets {
+        for b := &t.buckets[i]; b != nil; b = b.next {
+            for j := 0; j < entries_per_bucket; j++ {
+                if b.used & (1 << j) != 0 {
+                    res = append(res, dataset.Pair[K, V]{Key: b.key[j], Val: b.val[j]})
+                }
+            }
+        }
+    }
+    for i := range t.buckets {
+        t.buckets[i].lock.Revert()
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: size the table for the elements (two per bucket on average), then fill it
+    if set.Size() != 0 {
+        return share.Invalid_load("hashtable_clht_lb", "the set is not empty")
+    }
+    num_buckets := uint(len(set.table.Load().buckets))
+    for 2 * num_buckets < uint(len(pairs)) {
+        num_buckets *= 2
+    }
+    t := new_table[K, V](num_buckets)
+    var loaded uint = 0
+    for _, pair := range pairs {
+        head := &t.buckets[set.hasher(pair.Key) & t.hash]
+        if b, _ := head.search(pair.Key); b == nil {
+            t.put(head, pair.Key, pair.Val)
+            loaded++
+        }
+    }
+    set.table.Store(t)
+    set.count.Add(int64(loaded))
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
@@ -0,0 +1,390 @@
+/**
+ * @file   hashtable_clht_lf.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * CLHT-LF: lock-free cache-line hash table, with resizing, from
+ * Asynchronized Concurrency: The Secret to Scaling Concurrent Search Data Structures,
+ * Tudor David, Rachid Guerraoui, Vasileios Trigonakis,
+ * ASPLOS 2015.
+ *
+ * Each bucket holds a few elements in place, and a snapshot word: a version in
+ * the high 32 bits, then the state of each slot (invalid, inserting or valid)
+ * and a frozen bit in the low bits. An insertion reserves an invalid slot by a
+ * CAS on the snapshot, writes the element, then validates the slot while
+ * increasing the version; a deletion invalidates the slot by a single CAS, and a
+ * replacement validates the new slot while invalidating the old one. The
+ * searches copy the bucket, and validate the snapshot afterwards. There is no
+ * overflow bucket: an insertion into a full bucket doubles the table, which
+ * splits each bucket in two, after freezing every bucket of the old table. As in
+ * C, more than 3 keys sharing their whole hash would make the table grow forever.
+**/
+
+package hashtable_clht_lf
+
+import (
+    "runtime"
+    "sync"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    entries_per_bucket = 3 // With 8-byte keys and values, a bucket fills a 64-byte cache line
+
+    state_invalid = uint64(0)   // Free slot
+    state_inserting = uint64(1) // Slot reserved by an insertion, being written
+    state_valid = uint64(2)     // Slot holding an element
+    state_mask = uint64(3)
+
+    frozen_bit = uint64(1) << (2 * entries_per_bucket) // The bucket is being copied by a resize, and must not change anymore
+    version_shift = 32
+)
+
+// -----------------------------------------------------------------------------
+
+type slots[K comparable, V any] struct {
+    key [entries_per_bucket]K
+    val [entries_per_bucket]V
+}
+
+type bucket[K comparable, V any] struct {
+    snapshot atomic.Uint64 // Version, state of each slot and frozen bit
+    slots[K, V]
+}
+
+type table[K comparable, V any] struct {
+    hash uint
+    buckets []bucket[K, V]
+}
+
+type DataSet[K comparable, V any] struct {
+    hasher share.Hasher[K]
+    table atomic.Pointer[table[K, V]] // Current table
+    resize sync.Mutex                 // Held while resizing
+    count *counter.Striped            // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func get_state(snapshot uint64, j int) uint64 {
+    return snapshot >> (2 * j) & state_mask
+}
+
+func set_state(snapshot uint64, j int, state uint64) uint64 {
+    return snapshot &^ (state_mask << (2 * j)) | state << (2 * j)
+}
+
+func is_frozen(snapshot uint64) bool {
+    return snapshot & frozen_bit != 0
+}
+
+func is_inserting(snapshot uint64) bool { // Whether any slot is being written
+    for j := 0; j < entries_per_bucket; j++ {
+        if get_state(snapshot, j) == state_inserting {
+            return true
+        }
+    }
+    return false
+}
+
+func free_slot(snapshot uint64) int { // First invalid slot, -1 if none
+    for j := 0; j < entries_per_bucket; j++ {
+        if get_state(snapshot, j) == state_invalid {
+            return j
+        }
+    }
+    return -1
+}
+
+func new_table[K comparable, V any](num_buckets uint) *table[K, V] {
+    t := new(table[K, V])
+    t.hash = num_buckets - 1
+    t.buckets = make([]bucket[K, V], num_buckets)
+    return t
+}
+
+func (s *slots[K, V]) index(snapshot uint64, key K) int { // Valid slot holding 'key', -1 if none
+    for j := 0; j < entries_per_bucket; j++ {
+        if get_state(snapshot, j) == state_valid && s.key[j] == key {
+            return j
+        }
+    }
+    return -1
+}
+
+func (set *DataSet[K, V]) get_bucket(key K) (*table[K, V], *bucket[K, V]) { // Bucket of 'key' in the current table
+    t := set.table.Load()
+    return t, &t.buckets[set.hasher(key) & t.hash]
+}
+
+func (set *DataSet[K, V]) wait(t *table[K, V]) { // Wait for the resized table replacing 't' to get published
+    for set.table.Load() == t {
+        runtime.Gosched()
+    }
+}
+
+func (set *DataSet[K, V]) expand(t *table[K, V]) { // Double the table 't', unless another thread already did
+    set.resize.Lock()
+    defer set.resize.Unlock()
+    if set.table.Load() != t {
+        return
+    }
+    next := new_table[K, V](2 * uint(len(t.buckets)))
+    for i := range t.buckets {
+        b := &t.buckets[i]
+        snapshot := b.snapshot.Load()
+        for !b.snapshot.CompareAndSwap(snapshot, snapshot | frozen_bit) {
+            snapshot = b.snapshot.Load()
+        }
+        for j := 0; j < entries_per_bucket; j++ { // The elements being inserted are dropped, their insertions retry in the new table
+            if get_state(snapshot, j) != state_valid {
+                continue
+            }
+            dest := &next.buckets[set.hasher(b.key[j]) & next.hash]
+            dest_snapshot := dest.snapshot.Load()
+            k := free_slot(dest_snapshot) // Each bucket of the old table is split in two, so there is always room
+            dest.key[k] = b.key[j]
+            dest.val[k] = b.val[j]
+            dest.snapshot.Store(set_state(dest_snapshot, k, state_valid))
+        }
+    }
+    set.table.Store(next)
+}
+
+func (set *DataSet[K, V]) read(key K) (*table[K, V], *bucket[K, V], uint64, slots[K, V]) { // Consistent copy of the bucket of 'key', not frozen
+    for {
+        t, b := set.get_bucket(key)
+        snapshot := b.snapshot.Load()
+        if is_frozen(snapshot) {
+            set.wait(t)
+            continue
+        }
+        copy := b.slots
+        if b.snapshot.Load() == snapshot {
+            return t, b, snapshot, copy
+        }
+    }
+}
+
+func (set *DataSet[K, V]) store(t *table[K, V], b *bucket[K, V], snapshot uint64, key K, val V, old int) bool { // Store into a free slot, then invalidate the slot 'old' (if not -1) atomically, return false to retry
+    j := free_slot(snapshot)
+    if j < 0 { // Full bucket, a replacement needing a spare slot as well
+        set.expand(t)
+        return false
+    }
+    if !b.snapshot.CompareAndSwap(snapshot, set_state(snapshot, j, state_inserting)) {
+        return false
+    }
+    b.key[j] = key
+    b.val[j] = val
+    for { // Only the frozen bit and the other slots can change meanwhile, as no insertion proceeds while a slot is being written
+        snapshot = b.snapshot.Load()
+        if is_frozen(snapshot) { // Dropped by the resize
+            set.wait(t)
+            return false
+        }
+        if old >= 0 && get_state(snapshot, old) != state_valid { // Deleted meanwhile, release the slot
+            if b.snapshot.CompareAndSwap(snapshot, set_state(snapshot, j, state_invalid)) {
+                return false
+            }
+            continue
+        }
+        next := set_state(snapshot, j, state_valid) + 1 << version_shift
+        if old >= 0 {
+            next = set_state(next, old, state_invalid)
+        }
+        if b.snapshot.CompareAndSwap(snapshot, next) {
+            return true
+        }
+    }
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    for {
+        t, b, snapshot, copy := set.read(key)
+        if is_inserting(snapshot) { // The element being written could have the same key
+            runtime.Gosched()
+            continue
+        }
+        j := copy.index(snapshot, key)
+        found := j >= 0
+        var cur V
+        if found {
+            cur = copy.val[j]
+        }
+
+        val, action := fn(cur, found)
+        switch {
+        case action == share.ACTION_STORE:
+            if !set.store(t, b, snapshot, key, val, j) {
+                continue
+            }
+            if !found {
+                set.count.Add(1)
+            }
+            return val, true
+        case action == share.ACTION_DELETE && found:
+            if !b.snapshot.CompareAndSwap(snapshot, set_state(snapshot, j, state_invalid)) {
+                continue
+            }
+            set.count.Add(-1)
+            var zero V
+            return zero, false
+        default:
+            return cur, found
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses NumBuckets (initial amount) and AtomicSize
+    num_buckets := share.Or_default(opts.NumBuckets, share.DEFAULT_NUM_BUCKETS)
+    if !share.Is_pow2(num_buckets) {
+        return nil, share.Invalid_option("hashtable_clht_lf", "amount of buckets", num_buckets, "a power of 2")
+    }
+    set := new(DataSet[K, V])
+    set.hasher = share.NewHasher[K]()
+    set.table.Store(new_table[K, V](num_buckets))
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    t := set.table.Load()
+    for i := range t.buckets {
+        snapshot := t.buckets[i].snapshot.Load()
+        for j := 0; j < entries_per_bucket; j++ {
+            if get_state(snapshot, j) == state_valid {
+                size++
+            }
+        }
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    for {
+        _, b := set.get_bucket(key)
+        snapshot := b.snapshot.Load()
+        copy := b.slots
+        if b.snapshot.Load() != snapshot { // The copy may be inconsistent
+            continue
+        }
+        if j := copy.index(snapshot, key); j >= 0 {
+            return copy.val[j], true
+        }
+        var zero V
+        return zero, false
+    }
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    for {
+        t, b, snapshot, copy := set.read(key)
+        if is_inserting(snapshot) { // The element being written could have the same key
+            runtime.Gosched()
+            continue
+        }
+        if copy.index(snapshot, key) >= 0 {
+            return false
+        }
+        if set.store(t, b, snapshot, key, val, -1) {
+            set.count.Add(1)
+            return true
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    for {
+        _, b, snapshot, copy := set.read(key)
+        j := copy.index(snapshot, key)
+        if j < 0 {
+            var zero V
+            return zero, false
+        }
+        if b.snapshot.CompareAndSwap(snapshot, set_state(snapshot, j, state_invalid)) { // The slot keeps its stale element until reused
+            set.count.Add(-1)
+            return copy.val[j], true
+        }
+    }
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: size the table for the elements (two per bucket on average), doubling it until no bucket overflows
+    if set.Size() != 0 {
+        return share.Invalid_load("hashtable_clht_lf", "the set is not empty")
+    }
+    num_buckets := uint(len(set.table.Load().buckets))
+    for 2 * num_buckets < uint(len(pairs)) {
+        num_buckets *= 2
+    }
+retry:
+    t := new_table[K, V](num_buckets)
+    var loaded uint = 0
+    for _, pair := range pairs {
+        b := &t.buckets[set.hasher(pair.Key) & t.hash]
+        snapshot := b.snapshot.Load()
+        if b.index(snapshot, pair.Key) >= 0 {
+            continue
+        }
+        j := free_slot(snapshot)
+        if j < 0 {
+            num_buckets *= 2
+            goto retry
+        }
+        b.key[j] = pair.Key
+        b.val[j] = pair.Val
+        b.snapshot.Store(set_state(snapshot, j, state_valid))
+        loaded++
+    }
+    set.table.Store(t)
+    set.count.Add(int64(loaded))
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
@@ -0,0 +1,369 @@
+/**
+ * @file   hashtable_copy.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Similar to Java's CopyOnWriteArrayList. One array per bucket.
+ * http://docs.oracle.com/javase/7/docs/api/java/util/concurrent/CopyOnWriteArrayList.html
+**/
+
+package hashtable_copy
+
+import (
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
+)
+
+const (
+    read_only_fail bool = true
+)
+
+// -----------------------------------------------------------------------------
+
+type keyval[K comparable, V any] struct {
+    key K
+    val V
+}
+
+type array[K comparable, V any] struct {
+    size uint
+    table []keyval[K, V]
+}
+
+type DataSet[K comparable, V any] struct {
+    num_buckets uint
+    hash uint
+    hasher share.Hasher[K]
+    lock []ttas.Mutex
+    arrays []*array[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func new_array[K comparable, V any](size uint) *array[K, V] {
+    array := new(array[K, V])
+    array.size = size
+    array.table = make([]keyval[K, V], size)
+    return array
+}
+
+func (all_cur *array[K, V]) cpy_array_search(key K) bool {
+    for i := uint(0); i < all_cur.size; i++ {
+        if all_cur.table[i].key == key {
+            return true
+        }
+    }
+    return false
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    bucket := set.hasher(key) & set.hash
+    set.lock[bucket].Lock()
+    defer set.lock[bucket].Unlock()
+
+    all_old := set.arrays[bucket]
+    var pos uint // Position of the key, or size of the array if absent
+    for pos = 0; pos < all_old.size; pos++ {
+        if all_old.table[pos].key == key {
+            break
+        }
+    }
+    found := pos < all_old.size
+    var cur V
+    if found {
+        cur = all_old.table[pos].val
+    }
+
+    val, action := fn(cur, found)
+    switch {
+    case action == share.ACTION_STORE:
+        size := all_old.size
+        if !found {
+            size++
+        }
+        all_new := new_array[K, V](size)
+        copy(all_new.table, all_old.table)
+        all_new.table[pos].key = key
+        all_new.table[pos].val = val
+        set.arrays[bucket] = all_new
+        if !found {
+            set.count.Add(1)
+        }
+        return val, true
+    case action == share.ACTION_DELETE && found:
+        all_new := new_array[K, V](all_old.size - 1)
+        copy(all_new.table, all_old.table[:pos])
+        copy(all_new.table[pos:], all_old.table[pos + 1:])
+        set.arrays[bucket] = all_new
+        set.count.Add(-1)
+        var zero V
+        return zero, false
+    default:
+        return cur, found
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses NumBuckets and AtomicSize
+    num_buckets := share.Or_default(opts.NumBuckets, share.DEFAULT_NUM_BUCKETS)
+    if !share.Is_pow2(num_buckets) {
+        return nil, share.Invalid_option("hashtable_copy", "amount of buckets", num_buckets, "a power of 2")
+    }
+    set := new(DataSet[K, V])
+    set.num_buckets = num_buckets
+    set.hash = set.num_buckets - 1
+    set.hasher = share.NewHasher[K]()
+    set.lock = make([]ttas.Mutex, set.num_buckets)
+    set.arrays = make([]*array[K, V], set.num_buckets)
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.arrays[i] = new_array[K, V](0)
+    }
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var s uint = 0
+    for i := uint(0); i < set.num_buckets; i++ {
+        s += set.arrays[i].size
+    }
+    return s
+}
+
+func (set *DataSet[K, V]) Find(key K) (res V, ok bool) {
+    all_cur := set.arrays[set.hasher(key) & set.hash]
+    for i := uint(0); i < all_cur.size; i++ {
+        if all_cur.table[i].key == key {
+            return all_cur.table[i].val, true
+        }
+    }
+    return
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    bucket := set.hasher(key) & set.hash
+    var all_old *array[K, V]
+
+    if read_only_fail {
+        all_old = set.arrays[bucket]
+        if all_old.cpy_array_search(key) {
+            return false
+        }
+    }
+    set.lock[bucket].Lock()
+    defer set.lock[bucket].Unlock()
+
+    all_old = set.arrays[bucket]
+    all_new := new_array[K, V](all_old.size + 1)
+    var i uint
+    for i = 0; i < all_old.size; i++ {
+        if all_old.table[i].key == key {
+            return false
+        }
+        all_new.table[i].key = all_old.table[i].key
+        all_new.table[i].val = all_old.table[i].val
+    }
+    all_new.table[i].key = key
+    all_new.table[i].val = val
+    set.arrays[bucket] = all_new
+    set.count.Add(1)
+
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (result V, ok bool) {
+    bucket := set.hasher(key) & set.hash
+    var all_old *array[K, V]
+
+    if read_only_fail {
+        all_old = set.arrays[bucket]
+        if !all_old.cpy_array_search(key) {
+            return
+        }
+    }
+
+    set.lock[bucket].Lock()
+    defer set.lock[bucket].Unlock()
+    all_old = set.arrays[bucket]
+    all_new := new_array[K, V](all_old.size)
+
+    var i, n uint = 0, 0
+    for ; i < all_old.size; i++ {
+        if all_old.table[i].key == key {
+            result = all_old.table[i].val
+            all_new.size--
+            ok = true
+        } else {
+            all_new.table[n].key = all_old.table[i].key
+            all_new.table[n].val = all_old.table[i].val
+            n++
+        }
+    }
+
+    if ok {
+        set.arrays[bucket] = all_new
+        set.count.Add(-1)
+    }
+
+    return
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Lock every bucket in order, the arrays being immutable once published
+    all := make([]*array[K, V], set.num_buckets)
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.lock[i].Lock()
+    }
+    copy(all, set.arrays)
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.lock[i].Unlock()
+    }
+
+    var res []dataset.Pair[K, V]
+    for _, all_cur := range all {
+        for i := uint(0); i < all_cur.size; i++ {
+            res = append(res, dataset.Pair[K, V]{Key: all_cur.table[i].key, Val: all_cur.table[i].val})
+        }
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: allocate each array at the size of its share of the elements
+    if set.Size() != 0 {
+        return share.Invalid_load("hashtable_copy", "the set is not empty")
+    }
+    counts := make([]uint, set.num_buckets)
+    for _, pair := range pairs {
+        counts[set.hasher(pair.Key) & set.hash]++
+    }
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.arrays[i] = new_array[K, V](counts[i])
+        set.arrays[i].size = 0
+    }
+    var loaded uint = 0
+    for _, pair := range pairs {
+        all_cur := set.arrays[set.hasher(pair.Key) & set.hash]
+        if !all_cur.cpy_array_search(pair.Key) {
+            all_cur.table[all_cur.size] = keyval[K, V]{pair.Key, pair.Val}
+            all_cur.size++
+            loaded++
+        }
+    }
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.arrays[i].table = set.arrays[i].table[:set.arrays[i].size] // Repeated keys
+    }
+    set.count.Add(int64(loaded))
+    return nil
+}
+
+func (set *DataSet[K, V]) InsertBatch(pairs []dataset.Pair[K, V]) uint { // Lock each bucket once, copying its array once for all its new elements
+    buckets := make([]uint, len(pairs))
+    for i, pair := range pairs {
+        buckets[i] = set.hasher(pair.Key) & set.hash
+    }
+    var inserted uint = 0
+    for _, group := range share.Batch_groups(buckets) {
+        bucket := buckets[group[0]]
+        set.lock[bucket].Lock()
+        all_old := set.arrays[bucket]
+        all_new := new_array[K, V](all_old.size + uint(len(group)))
+        copy(all_new.table, all_old.table[:all_old.size])
+        all_new.size = all_old.size
+        for _, i := range group {
+            if !all_new.cpy_array_search(pairs[i].Key) {
+                all_new.table[all_new.size] = keyval[K, V]{pairs[i].Key, pairs[i].Val}
+                all_new.size++
+            }
+        }
+        if all_new.size > all_old.size {
+            all_new.table = all_new.table[:all_new.size]
+            set.arrays[bucket] = all_new
+            inserted += all_new.size - all_old.size
+        }
+        set.lock[bucket].Unlock()
+    }
+    set.count.Add(int64(inserted))
+    return inserted
+}
+
+func (set *DataSet[K, V]) DeleteBatch(keys []K) []dataset.Pair[K, V] { // Lock each bucket once, copying its array once for all its deleted elements
+    buckets := make([]uint, len(keys))
+    for i, key := range keys {
+        buckets[i] = set.hasher(key) & set.hash
+    }
+    var res []dataset.Pair[K, V]
+    for _, group := range share.Batch_groups(buckets) {
+        bucket := buckets[group[0]]
+        set.lock[bucket].Lock()
+        all_old := set.arrays[bucket]
+        all_new := new_array[K, V](all_old.size)
+        all_new.size = 0
+        for i := uint(0); i < all_old.size; i++ {
+            elem := all_old.table[i]
+            deleted := false
+            for _, j := range group {
+                if keys[j] == elem.key {
+                    deleted = true
+                    break
+                }
+            }
+            if deleted {
+                res = append(res, dataset.Pair[K, V]{Key: elem.key, Val: elem.val})
+            } else {
+                all_new.table[all_new.size] = elem
+                all_new.size++
+            }
+        }
+        if all_new.size < all_old.size {
+            all_new.table = all_new.table[:all_new.size]
+            set.arrays[bucket] = all_new
+        }
+        set.lock[bucket].Unlock()
+    }
+    set.count.Add(-int64(len(res)))
+    return res
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
@@ -0,0 +1,196 @@
+/**
+ * @file   hashtable_go_sequential.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Hashtable with buckets of native map structure.
+ * Sequential implementation.
+**/
+
+package hashtable_go_sequential
+
+import (
+    "sync"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+type bucket[K comparable, V any] struct {
+    lock sync.Mutex
+    set map[K]V
+}
+
+type DataSet[K comparable, V any] struct {
+    num_buckets uint
+    buckets []bucket[K, V]
+    hasher share.Hasher[K]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) getBucket(key K) *bucket[K, V] {
+    return &set.buckets[set.hasher(key) % set.num_buckets]
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    bucket := set.getBucket(key)
+    bucket.lock.Lock()
+    defer bucket.lock.Unlock()
+    cur, found := bucket.set[key]
+    val, action := fn(cur, found)
+    switch action {
+    case share.ACTION_STORE:
+        bucket.set[key] = val
+        if !found {
+            set.count.Add(1)
+        }
+        return val, true
+    case share.ACTION_DELETE:
+        delete(bucket.set, key)
+        if found {
+            set.count.Add(-1)
+        }
+        var zero V
+        return zero, false
+    default:
+        return cur, found
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses NumBuckets and AtomicSize
+    set := new(DataSet[K, V])
+    set.num_buckets = share.Or_default(opts.NumBuckets, share.DEFAULT_NUM_BUCKETS)
+    set.buckets = make([]bucket[K, V], set.num_buckets)
+    set.hasher = share.NewHasher[K]()
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.buckets[i].set = make(map[K]V)
+    }
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    for i := uint(0); i < set.num_buckets; i++ {
+        bucket := &set.buckets[i]
+        bucket.lock.Lock()
+        size += uint(len(bucket.set))
+        bucket.lock.Unlock()
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (res V, ok bool) {
+    bucket := set.getBucket(key)
+    bucket.lock.Lock()
+    defer bucket.lock.Unlock()
+    res, ok = bucket.set[key]
+    return
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    bucket := set.getBucket(key)
+    bucket.lock.Lock()
+    defer bucket.lock.Unlock()
+    _, has := bucket.set[key]
+    if has {
+        return false
+    }
+    bucket.set[key] = val
+    set.count.Add(1)
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (res V, ok bool) {
+    bucket := set.getBucket(key)
+    bucket.lock.Lock()
+    defer bucket.lock.Unlock()
+    res, ok = bucket.set[key]
+    if !ok {
+        return
+    }
+    delete(bucket.set, key)
+    set.count.Add(-1)
+    return
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Lock every bucket, in order
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.buckets[i].lock.Lock()
+    }
+    var res []dataset.Pair[K, V]
+    for i := uint(0); i < set.num_buckets; i++ {
+        for key, val := range set.buckets[i].set {
+            res = append(res, dataset.Pair[K, V]{Key: key, Val: val})
+        }
+        set.buckets[i].lock.Unlock()
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: allocate each map at the size of its share of the elements
+    if set.Size() != 0 {
+        return share.Invalid_load("hashtable_go_sequential", "the set is not empty")
+    }
+    counts := make([]int, set.num_buckets)
+    for _, pair := range pairs {
+        counts[set.hasher(pair.Key) % set.num_buckets]++
+    }
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.buckets[i].set = make(map[K]V, counts[i])
+    }
+    var loaded int64 = 0
+    for _, pair := range pairs {
+        bucket := set.getBucket(pair.Key)
+        if _, has := bucket.set[pair.Key]; !has {
+            bucket.set[pair.Key] = pair.Val
+            loaded++
+        }
+    }
+    set.count.Add(loaded)
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
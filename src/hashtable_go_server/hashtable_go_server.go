@@ -0,0 +1,289 @@
+/**
+ * @file   hashtable_go_server.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Hashtable with native map structure.
+ * One "server" goroutine, using channels.
+ *
+ * This implementation should be used like:
+ *   ch := set.<Op>Async()
+ *   <do something else>
+ *   res := <-ch // Only when first needed
+ * Here it is not the case, so useful only to estimate overhead.
+**/
+
+package hashtable_go_server
+
+import (
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    query_buffer_size uint = 16
+)
+
+// -----------------------------------------------------------------------------
+
+type bucket[K comparable, V any] struct {
+    queries chan interface{}
+    set map[K]V
+}
+
+type DataSet[K comparable, V any] struct {
+    num_buckets uint
+    buckets []bucket[K, V]
+    hasher share.Hasher[K]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// Result types
+type SizeAsyncRes struct {
+    size uint
+}
+type FindAsyncRes[V any] struct {
+    res V
+    ok bool
+}
+type InsertAsyncRes struct {
+    ok bool
+}
+type DeleteAsyncRes[V any] struct {
+    res V
+    ok bool
+}
+type UpdateAsyncRes[V any] struct {
+    res V
+    ok bool
+}
+
+// Call types
+type SizeAsyncCall struct {
+    res chan<- SizeAsyncRes
+}
+type FindAsyncCall[K comparable, V any] struct {
+    key K
+    res chan<- FindAsyncRes[V]
+}
+type InsertAsyncCall[K comparable, V any] struct {
+    key K
+    val V
+    res chan<- InsertAsyncRes
+}
+type DeleteAsyncCall[K comparable, V any] struct {
+    key K
+    res chan<- DeleteAsyncRes[V]
+}
+type UpdateAsyncCall[K comparable, V any] struct {
+    key K
+    fn func(val V, ok bool) (V, share.Action)
+    res chan<- UpdateAsyncRes[V]
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) getBucket(key K) *bucket[K, V] {
+    return &set.buckets[set.hasher(key) % set.num_buckets]
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) SizeAsync() <-chan SizeAsyncRes {
+    res := make(chan SizeAsyncRes, 1)
+    go func() {
+        queries := make([](chan SizeAsyncRes), set.num_buckets)
+        var sum uint = 0
+        for i := uint(0); i < set.num_buckets; i++ { // Queries
+            queries[i] = make(chan SizeAsyncRes, 1)
+            set.buckets[i].queries <- &SizeAsyncCall{queries[i]}
+        }
+        for i := uint(0); i < set.num_buckets; i++ { // Collect
+            sum += (<-queries[i]).size
+        }
+        res <- SizeAsyncRes{sum}
+    }()
+    return res
+}
+
+func (set *DataSet[K, V]) FindAsync(key K) <-chan FindAsyncRes[V] {
+    res := make(chan FindAsyncRes[V], 1)
+    set.getBucket(key).queries <- &FindAsyncCall[K, V]{key, res}
+    return res
+}
+
+func (set *DataSet[K, V]) InsertAsync(key K, val V) <-chan InsertAsyncRes {
+    res := make(chan InsertAsyncRes, 1)
+    set.getBucket(key).queries <- &InsertAsyncCall[K, V]{key, val, res}
+    return res
+}
+
+func (set *DataSet[K, V]) DeleteAsync(key K) <-chan DeleteAsyncRes[V] {
+    res := make(chan DeleteAsyncRes[V], 1)
+    set.getBucket(key).queries <- &DeleteAsyncCall[K, V]{key, res}
+    return res
+}
+
+func (set *DataSet[K, V]) UpdateAsync(key K, fn func(val V, ok bool) (V, share.Action)) <-chan UpdateAsyncRes[V] { // 'fn' runs in the server goroutine
+    res := make(chan UpdateAsyncRes[V], 1)
+    set.getBucket(key).queries <- &UpdateAsyncCall[K, V]{key, fn, res}
+    return res
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses NumBuckets and AtomicSize
+    set := new(DataSet[K, V])
+    set.num_buckets = share.Or_default(opts.NumBuckets, share.DEFAULT_NUM_BUCKETS)
+    set.buckets = make([]bucket[K, V], set.num_buckets)
+    set.hasher = share.NewHasher[K]()
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.buckets[i].set = make(map[K]V)
+        set.buckets[i].queries = make(chan interface{}, query_buffer_size)
+        go func(bucket *bucket[K, V]) { // Server goroutine
+            for {
+                anyquery := <-bucket.queries
+                if anyquery == nil {
+                    return
+                }
+                switch query := anyquery.(type) {
+                case *SizeAsyncCall:
+                    query.res <- SizeAsyncRes{uint(len(bucket.set))}
+                case *FindAsyncCall[K, V]:
+                    val, ok := bucket.set[query.key]
+                    query.res <- FindAsyncRes[V]{val, ok}
+                case *InsertAsyncCall[K, V]:
+                    _, has := bucket.set[query.key]
+                    if has {
+                        query.res <- InsertAsyncRes{false}
+                        continue
+                    }
+                    bucket.set[query.key] = query.val
+                    set.count.Add(1)
+                    query.res <- InsertAsyncRes{true}
+                case *DeleteAsyncCall[K, V]:
+                    val, ok := bucket.set[query.key]
+                    if !ok {
+                        query.res <- DeleteAsyncRes[V]{ok: false}
+                        continue
+                    }
+                    delete(bucket.set, query.key)
+                    set.count.Add(-1)
+                    query.res <- DeleteAsyncRes[V]{val, true}
+                case *UpdateAsyncCall[K, V]:
+                    cur, found := bucket.set[query.key]
+                    val, action := query.fn(cur, found)
+                    switch action {
+                    case share.ACTION_STORE:
+                        bucket.set[query.key] = val
+                        if !found {
+                            set.count.Add(1)
+                        }
+                        query.res <- UpdateAsyncRes[V]{val, true}
+                    case share.ACTION_DELETE:
+                        delete(bucket.set, query.key)
+                        if found {
+                            set.count.Add(-1)
+                        }
+                        query.res <- UpdateAsyncRes[V]{ok: false}
+                    default:
+                        query.res <- UpdateAsyncRes[V]{cur, found}
+                    }
+                default:
+                    panic("Unknow query")
+                }
+            }
+        }(&set.buckets[i])
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+    for i := uint(0); i < set.num_buckets; i++ {
+        close(set.buckets[i].queries)
+    }
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    res := <-set.SizeAsync()
+    return res.size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    res := <-set.FindAsync(key)
+    return res.res, res.ok
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    res := <-set.InsertAsync(key, val)
+    return res.ok
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    res := <-set.DeleteAsync(key)
+    return res.res, res.ok
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: allocate each map at the size of its share of the elements, the server goroutines seeing them from their next query on
+    if set.Size() != 0 {
+        return share.Invalid_load("hashtable_go_server", "the set is not empty")
+    }
+    counts := make([]int, set.num_buckets)
+    for _, pair := range pairs {
+        counts[set.hasher(pair.Key) % set.num_buckets]++
+    }
+    for i := uint(0); i < set.num_buckets; i++ {
+        set.buckets[i].set = make(map[K]V, counts[i])
+    }
+    var loaded int64 = 0
+    for _, pair := range pairs {
+        bucket := set.getBucket(pair.Key)
+        if _, has := bucket.set[pair.Key]; !has {
+            bucket.set[pair.Key] = pair.Val
+            loaded++
+        }
+    }
+    set.count.Add(loaded)
+    return nil
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) {
+    res := <-set.UpdateAsync(key, fn)
+    return res.res, res.ok
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
@@ -0,0 +1,178 @@
+/**
+ * @file   hashtable_go_syncmap.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Adapter of the standard library 'sync.Map'.
+ *
+ * The values are stored boxed, so that the updates of the map can be
+ * compare-and-swaps on the boxes, which are comparable whatever the value type.
+ * 'sync.Map' has no consistent iteration, hence no snapshot.
+**/
+
+package hashtable_go_syncmap
+
+import (
+    "sync"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+type DataSet[K comparable, V any] struct {
+    set sync.Map           // Key to boxed value (*V)
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) load(key K) (*V, bool) {
+    box, ok := set.set.Load(key)
+    if !ok {
+        return nil, false
+    }
+    return box.(*V), true
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    for {
+        box, found := set.load(key)
+        var cur V
+        if found {
+            cur = *box
+        }
+        val, action := fn(cur, found)
+        switch {
+        case action == share.ACTION_STORE && found:
+            if !set.set.CompareAndSwap(key, box, &val) {
+                continue
+            }
+        case action == share.ACTION_STORE:
+            if _, loaded := set.set.LoadOrStore(key, &val); loaded {
+                continue
+            }
+            set.count.Add(1)
+        case action == share.ACTION_DELETE && found:
+            if !set.set.CompareAndDelete(key, box) {
+                continue
+            }
+            set.count.Add(-1)
+        default:
+            return cur, found
+        }
+        if action == share.ACTION_DELETE {
+            var zero V
+            return zero, false
+        }
+        return val, true
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    set.set.Range(func(key any, box any) bool {
+        size++
+        return true
+    })
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    box, ok := set.load(key)
+    if !ok {
+        var zero V
+        return zero, false
+    }
+    return *box, true
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    if _, loaded := set.set.LoadOrStore(key, &val); loaded {
+        return false
+    }
+    set.count.Add(1)
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    box, ok := set.set.LoadAndDelete(key)
+    if !ok {
+        var zero V
+        return zero, false
+    }
+    set.count.Add(-1)
+    return *box.(*V), true
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: store each element, keeping the first of repeated keys
+    if set.Size() != 0 {
+        return share.Invalid_load("hashtable_go_syncmap", "the set is not empty")
+    }
+    for _, pair := range pairs {
+        set.Insert(pair.Key, pair.Val)
+    }
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    box, loaded := set.set.Swap(key, &val)
+    if !loaded {
+        set.count.Add(1)
+        var zero V
+        return zero, false
+    }
+    return *box.(*V), true
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    box, loaded := set.set.LoadOrStore(key, &val)
+    if !loaded {
+        set.count.Add(1)
+        return val, false
+    }
+    return *box.(*V), true
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
@@ -0,0 +1,490 @@
+/**
+ * @file   hashtable_java.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Similar to Java's ConcurrentHashMap.
+ * Doug Lea. 1.3.4. http://gee.cs.oswego.edu/dl/classes/EDU/oswego/
+ * cs/dl/util/concurrent/intro.html, 2003.
+**/
+
+package hashtable_java
+
+import (
+    "runtime"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
+)
+
+const (
+    base_load_factor float32 = 1
+    read_only_fail bool = true
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K comparable, V any] struct {
+    key K
+    val V
+    next *node[K, V]
+}
+
+type segment[K comparable, V any] struct {
+    num_buckets uint
+    hash uint
+    lock ttas.Mutex
+    modifications uint32
+    size uint32
+    load_factor float32
+    size_limit uint32
+    table []*node[K, V]
+}
+
+type DataSet[K comparable, V any] struct {
+    num_segments uint
+    hash uint
+    hash_seed uint
+    hasher share.Hasher[K]
+    segments []*segment[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func uintLog2(nb uint) uint {
+    var r uint = 0
+    for nb >= 2 {
+        r++
+        nb = nb >> 1
+    }
+    return r
+}
+
+func hash(hash uint, hash_seed uint) uint {
+    return hash >> hash_seed
+}
+
+// -----------------------------------------------------------------------------
+
+func new_node[K comparable, V any](key K, val V, next *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.key = key
+    node.val = val
+    node.next = next
+    return node
+}
+
+func new_segment[K comparable, V any](capacity uint, load_factor float32) *segment[K, V] {
+    seg := new(segment[K, V])
+    seg.table = make([]*node[K, V], capacity)
+    seg.num_buckets = capacity
+    seg.hash = capacity - 1
+    seg.modifications = 0
+    seg.size = 0
+    seg.load_factor = load_factor
+    seg.size_limit = uint32(seg.load_factor * float32(capacity))
+    if seg.size_limit == 0 {
+        seg.size_limit = 1
+    }
+    return seg
+}
+
+func (set *DataSet[K, V]) segment_rehash(seg_num uint, newn *node[K, V]) {
+    seg_old := set.segments[seg_num]
+    seg_new := new_segment[K, V](seg_old.num_buckets << 1, seg_old.load_factor)
+    mask_new := seg_new.hash
+    for b := uint(0); b < seg_old.num_buckets; b++ {
+        curr := seg_old.table[b]
+        if curr != nil {
+            next := curr.next
+            idx := hash(set.hasher(curr.key), set.hash_seed) & mask_new
+            if next == nil { /* single node on list */
+                seg_new.table[idx] = curr
+            } else { /* reuse consecutive sequence at same slot */
+                last_run := curr
+                last_idx := idx
+                for last := next; last != nil; last = last.next {
+                    k := hash(set.hasher(last.key), set.hash_seed) & mask_new
+                    if k != last_idx {
+                        last_idx = k
+                        last_run = last
+                    }
+                }
+                seg_new.table[last_idx] = last_run
+                /* clone remaining */
+                for p := curr; p != last_run; p = p.next {
+                    k := hash(set.hasher(p.key), set.hash_seed) & mask_new
+                    seg_new.table[k] = new_node(p.key, p.val, seg_new.table[k])
+                }
+            }
+        }
+    }
+    new_idx := hash(set.hasher(newn.key), set.hash_seed) & mask_new; /* add the new node */
+    newn.next = seg_new.table[new_idx]
+    seg_new.table[new_idx] = newn
+    seg_new.size = seg_old.size + 1
+    set.segments[seg_num] = seg_new
+}
+
+func (set *DataSet[K, V]) contains(seg *segment[K, V], key K) bool {
+    curr := seg.table[hash(set.hasher(key), set.hash_seed) & seg.hash]
+    for curr != nil {
+        if curr.key == key {
+            return true
+        }
+        curr = curr.next
+    }
+    return false
+}
+
+func (set *DataSet[K, V]) lock_segment(seg_num uint) *segment[K, V] { // Lock the current segment of the given number (a rehashed segment stays locked)
+    for {
+        seg := (*segment[K, V])(volatile.ReadPointer((*unsafe.Pointer)(unsafe.Pointer(&set.segments[seg_num]))))
+        if seg.lock.TryLock() {
+            return seg
+        }
+        runtime.Gosched()
+    }
+}
+
+func (set *DataSet[K, V]) insert_locked(seg_num uint, seg *segment[K, V], h uint, key K, val V) (inserted bool, rehashed bool) { // Insert in the locked segment, which stays locked if rehashed (the new segment being unlocked)
+    bucket := &seg.table[hash(h, set.hash_seed) & seg.hash]
+    curr := *bucket
+    var pred *node[K, V]
+    for curr != nil {
+        if curr.key == key {
+            return false, false
+        }
+        pred = curr
+        curr = curr.next
+    }
+    n := new_node(key, val, nil)
+    sizepp := seg.size + 1
+    if sizepp >= seg.size_limit {
+        set.segment_rehash(seg_num, n)
+        return true, true
+    }
+    if pred != nil {
+        pred.next = n
+    } else {
+        *bucket = n
+    }
+    seg.size = sizepp
+    return true, false
+}
+
+func (set *DataSet[K, V]) delete_locked(seg *segment[K, V], h uint, key K) (result V, ok bool) { // Delete from the locked segment
+    bucket := &seg.table[hash(h, set.hash_seed) & seg.hash]
+    curr := *bucket
+    var pred *node[K, V]
+    for curr != nil {
+        if curr.key == key {
+            /* do the remove */
+            if pred != nil {
+                pred.next = curr.next
+            } else {
+                *bucket = curr.next
+            }
+            seg.size--
+            return curr.val, true
+        }
+        pred = curr
+        curr = curr.next
+    }
+    return
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    h := set.hasher(key)
+    seg_num := h & set.hash
+    seg := set.lock_segment(seg_num)
+
+    bucket := &seg.table[hash(h, set.hash_seed) & seg.hash]
+    curr := *bucket
+    var pred *node[K, V]
+    for curr != nil && curr.key != key {
+        pred = curr
+        curr = curr.next
+    }
+    found := curr != nil
+    var cur V
+    if found {
+        cur = curr.val
+    }
+
+    val, action := fn(cur, found)
+    var link *node[K, V] // New successor of 'pred'
+    switch {
+    case action == share.ACTION_STORE && found: /* replace the node, as readers do not lock */
+        link = new_node(key, val, curr.next)
+    case action == share.ACTION_STORE:
+        n := new_node(key, val, nil)
+        sizepp := seg.size + 1
+        set.count.Add(1)
+        if sizepp >= seg.size_limit {
+            set.segment_rehash(seg_num, n)
+            return val, true
+        }
+        link = n
+        seg.size = sizepp
+    case action == share.ACTION_DELETE && found:
+        link = curr.next
+        seg.size--
+        set.count.Add(-1)
+    default:
+        seg.lock.Unlock()
+        return cur, found
+    }
+    if pred != nil {
+        pred.next = link
+    } else {
+        *bucket = link
+    }
+    seg.lock.Unlock()
+
+    if action == share.ACTION_STORE {
+        return val, true
+    }
+    var zero V
+    return zero, false
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses Capacity, Concurrency and AtomicSize
+    capacity := share.Or_default(opts.Capacity, share.DEFAULT_CAPACITY)
+    concurrency := share.Or_default(opts.Concurrency, share.DEFAULT_CONCURRENCY)
+    if !share.Is_pow2(concurrency) {
+        return nil, share.Invalid_option("hashtable_java", "concurrency level", concurrency, "a power of 2")
+    }
+    if capacity < concurrency {
+        capacity = concurrency
+    }
+    set := new(DataSet[K, V])
+    set.num_segments = concurrency
+    set.segments = make([]*segment[K, V], set.num_segments)
+    set.hash = set.num_segments - 1
+    set.hash_seed = uintLog2(set.num_segments)
+    set.hasher = share.NewHasher[K]()
+    capacity_seg := capacity / set.num_segments
+    for s := uint(0); s < set.num_segments; s++ {
+        set.segments[s] = new_segment[K, V](capacity_seg, base_load_factor)
+    }
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    for s := uint(0); s < set.num_segments; s++ {
+        seg := set.segments[s]
+        for i := uint(0); i < seg.num_buckets; i++ {
+            curr := seg.table[i]
+            for curr != nil {
+                size++
+                curr = curr.next
+            }
+        }
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (res V, ok bool) {
+    h := set.hasher(key)
+    seg := set.segments[h & set.hash]
+    curr := seg.table[hash(h, set.hash_seed) & seg.hash]
+    for curr != nil {
+        if curr.key == key {
+            return curr.val, true
+        }
+        curr = curr.next
+    }
+    return
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var seg *segment[K, V]
+    h := set.hasher(key)
+    seg_num := h & set.hash
+
+    if read_only_fail {
+        seg = set.segments[seg_num]
+        if set.contains(seg, key) {
+            return false
+        }
+    }
+
+    seg = set.lock_segment(seg_num)
+    inserted, rehashed := set.insert_locked(seg_num, seg, h, key, val)
+    if !rehashed {
+        seg.lock.Unlock()
+    }
+    if inserted {
+        set.count.Add(1)
+    }
+    return inserted
+}
+
+func (set *DataSet[K, V]) Delete(key K) (result V, ok bool) {
+    var seg *segment[K, V]
+    h := set.hasher(key)
+    seg_num := h & set.hash
+
+    if read_only_fail {
+        seg = set.segments[seg_num]
+        if !set.contains(seg, key) {
+            return
+        }
+    }
+
+    seg = set.lock_segment(seg_num)
+    result, ok = set.delete_locked(seg, h, key)
+    seg.lock.Unlock()
+    if ok {
+        set.count.Add(-1)
+    }
+    return
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Lock every segment in order, which also prevents any rehash
+    segs := make([]*segment[K, V], set.num_segments)
+    for s := uint(0); s < set.num_segments; s++ {
+        segs[s] = set.lock_segment(s)
+    }
+    var res []dataset.Pair[K, V]
+    for _, seg := range segs {
+        for i := uint(0); i < seg.num_buckets; i++ {
+            for curr := seg.table[i]; curr != nil; curr = curr.next {
+                res = append(res, dataset.Pair[K, V]{Key: curr.key, Val: curr.val})
+            }
+        }
+        seg.lock.Unlock()
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: grow each segment for its share of the elements, so that none gets rehashed
+    if set.Size() != 0 {
+        return share.Invalid_load("hashtable_java", "the set is not empty")
+    }
+    counts := make([]uint32, set.num_segments)
+    for _, pair := range pairs {
+        counts[set.hasher(pair.Key) & set.hash]++
+    }
+    for s := uint(0); s < set.num_segments; s++ {
+        seg := set.segments[s]
+        capacity := seg.num_buckets
+        for uint32(seg.load_factor * float32(capacity)) <= counts[s] {
+            capacity <<= 1
+        }
+        if capacity != seg.num_buckets {
+            set.segments[s] = new_segment[K, V](capacity, seg.load_factor)
+        }
+    }
+    var loaded int64 = 0
+    for _, pair := range pairs {
+        h := set.hasher(pair.Key)
+        seg := set.segments[h & set.hash]
+        if !set.contains(seg, pair.Key) {
+            bucket := &seg.table[hash(h, set.hash_seed) & seg.hash]
+            *bucket = new_node(pair.Key, pair.Val, *bucket)
+            seg.size++
+            loaded++
+        }
+    }
+    set.count.Add(loaded)
+    return nil
+}
+
+func (set *DataSet[K, V]) InsertBatch(pairs []dataset.Pair[K, V]) uint { // Lock each segment once (and once more after each rehash)
+    hashes := make([]uint, len(pairs))
+    segs := make([]uint, len(pairs))
+    for i, pair := range pairs {
+        hashes[i] = set.hasher(pair.Key)
+        segs[i] = hashes[i] & set.hash
+    }
+    var inserted uint = 0
+    for _, group := range share.Batch_groups(segs) {
+        seg_num := segs[group[0]]
+        seg := set.lock_segment(seg_num)
+        for _, i := range group {
+            ok, rehashed := set.insert_locked(seg_num, seg, hashes[i], pairs[i].Key, pairs[i].Val)
+            if ok {
+                inserted++
+            }
+            if rehashed {
+                seg = set.lock_segment(seg_num)
+            }
+        }
+        seg.lock.Unlock()
+    }
+    set.count.Add(int64(inserted))
+    return inserted
+}
+
+func (set *DataSet[K, V]) DeleteBatch(keys []K) []dataset.Pair[K, V] { // Lock each segment once
+    hashes := make([]uint, len(keys))
+    segs := make([]uint, len(keys))
+    for i, key := range keys {
+        hashes[i] = set.hasher(key)
+        segs[i] = hashes[i] & set.hash
+    }
+    var res []dataset.Pair[K, V]
+    for _, group := range share.Batch_groups(segs) {
+        seg := set.lock_segment(segs[group[0]])
+        for _, i := range group {
+            if val, ok := set.delete_locked(seg, hashes[i], keys[i]); ok {
+                res = append(res, dataset.Pair[K, V]{Key: keys[i], Val: val})
+            }
+        }
+        seg.lock.Unlock()
+    }
+    set.count.Add(-int64(len(res)))
+    return res
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
@@ -0,0 +1,189 @@
+/**
+ * @file   hashtable_lists.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Hash tables whose buckets are concurrent linked lists, as compared in
+ * Asynchronized Concurrency: The Secret to Scaling Concurrent Search Data Structures,
+ * Tudor David, Rachid Guerraoui, Vasileios Trigonakis,
+ * ASPLOS 2015.
+ *
+ * The table is generic over the list algorithm: each bucket is a whole instance
+ * of the list, synchronized on its own, so that the table adds no
+ * synchronization but the (optional) counter of elements.
+**/
+
+package hashtable_lists
+
+import (
+    "cmp"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_harris_opt"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_lazy"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_optik"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_pugh"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+type DataSet[K cmp.Ordered, V any, L dataset.Map[K, V]] struct { // Keys are ordered, as each bucket is a sorted list
+    name string // Name of the table, for the errors
+    hash uint
+    hasher share.Hasher[K]
+    buckets []L
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V, L]) bucket(key K) L {
+    return set.buckets[set.hasher(key) & set.hash]
+}
+
+// -----------------------------------------------------------------------------
+
+/** Build a hash table whose buckets are instances of the given list algorithm.
+ * @param name     Name of the table, for the errors
+ * @param new_list Constructor of the list algorithm
+ * @param opts     Options of the table: NumBuckets (power of 2) and AtomicSize
+ * @return Hash table, or the error of the options or of the list constructor
+**/
+func New[K cmp.Ordered, V any, L dataset.Map[K, V]](name string, new_list func(share.Options) (L, error), opts share.Options) (*DataSet[K, V, L], error) {
+    num_buckets := share.Or_default(opts.NumBuckets, share.DEFAULT_NUM_BUCKETS)
+    if !share.Is_pow2(num_buckets) {
+        return nil, share.Invalid_option(name, "amount of buckets", num_buckets, "a power of 2")
+    }
+    set := new(DataSet[K, V, L])
+    set.name = name
+    set.hash = num_buckets - 1
+    set.hasher = share.NewHasher[K]()
+    set.buckets = make([]L, num_buckets)
+    for i := range set.buckets {
+        list, err := new_list(share.Options{}) // The table counts the elements itself
+        if err != nil {
+            return nil, err
+        }
+        set.buckets[i] = list
+    }
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func NewLazy[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V, *linkedlist_lazy.DataSet[K, V]], error) { // Uses NumBuckets and AtomicSize
+    return New("hashtable_lazy", linkedlist_lazy.New[K, V], opts)
+}
+
+func NewHarrisOpt[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V, *linkedlist_harris_opt.DataSet[K, V]], error) { // Uses NumBuckets and AtomicSize
+    return New("hashtable_harris_opt", linkedlist_harris_opt.New[K, V], opts)
+}
+
+func NewPugh[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V, *linkedlist_pugh.DataSet[K, V]], error) { // Uses NumBuckets and AtomicSize
+    return New("hashtable_pugh", linkedlist_pugh.New[K, V], opts)
+}
+
+func NewOptik[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V, *linkedlist_optik.DataSet[K, V]], error) { // Uses NumBuckets and AtomicSize
+    return New("hashtable_optik", linkedlist_optik.New[K, V], opts)
+}
+
+func (set *DataSet[K, V, L]) Destroy() {
+    for _, list := range set.buckets {
+        list.Destroy()
+    }
+}
+
+func (set *DataSet[K, V, L]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    for _, list := range set.buckets {
+        size += list.Size()
+    }
+    return size
+}
+
+func (set *DataSet[K, V, L]) Find(key K) (V, bool) {
+    return set.bucket(key).Find(key)
+}
+
+func (set *DataSet[K, V, L]) Insert(key K, val V) bool {
+    if !set.bucket(key).Insert(key, val) {
+        return false
+    }
+    set.count.Add(1)
+    return true
+}
+
+func (set *DataSet[K, V, L]) Delete(key K) (V, bool) {
+    val, ok := set.bucket(key).Delete(key)
+    if ok {
+        set.count.Add(-1)
+    }
+    return val, ok
+}
+
+func (set *DataSet[K, V, L]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: insert each element in its bucket, keeping the first of repeated keys
+    if set.Size() != 0 {
+        return share.Invalid_load(set.name, "the set is not empty")
+    }
+    for _, pair := range pairs {
+        set.Insert(pair.Key, pair.Val)
+    }
+    return nil
+}
+
+func (set *DataSet[K, V, L]) Put(key K, val V) (V, bool) {
+    old, ok := set.bucket(key).Put(key, val)
+    if !ok {
+        set.count.Add(1)
+    }
+    return old, ok
+}
+
+func (set *DataSet[K, V, L]) CompareAndSwap(key K, old V, new V) bool {
+    return set.bucket(key).CompareAndSwap(key, old, new)
+}
+
+func (set *DataSet[K, V, L]) LoadOrStore(key K, val V) (V, bool) {
+    res, loaded := set.bucket(key).LoadOrStore(key, val)
+    if !loaded {
+        set.count.Add(1)
+    }
+    return res, loaded
+}
+
+func (set *DataSet[K, V, L]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    var found bool // Presence seen by the last call of 'fn', the one taking effect
+    val, ok := set.bucket(key).Compute(key, func(cur V, ok bool) (V, bool) {
+        found = ok
+        return fn(cur, ok)
+    })
+    if ok && !found {
+        set.count.Add(1)
+    } else if !ok && found {
+        set.count.Add(-1)
+    }
+    return val, ok
+}
@@ -0,0 +1,133 @@
+/**
+ * @file   hashtable_lock.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Global-lock baseline: the Go map hash table ('hashtable_go_sequential')
+ * behind a single 'sync.Mutex', or a 'sync.RWMutex' whose searches share the
+ * lock. The bucket locks of the wrapped table are thus never contended.
+**/
+
+package hashtable_lock
+
+import (
+    "sync"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_go_sequential"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+type DataSet[K comparable, V any] struct {
+    lock sync.Locker  // Held by the updates
+    rlock sync.Locker // Held by the searches, shared among them for a 'sync.RWMutex'
+    set *hashtable_go_sequential.DataSet[K, V] // Counting its elements itself, if 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func new_set[K comparable, V any](opts share.Options, lock sync.Locker, rlock sync.Locker) (*DataSet[K, V], error) {
+    inner, err := hashtable_go_sequential.New[K, V](opts)
+    if err != nil {
+        return nil, err
+    }
+    set := new(DataSet[K, V])
+    set.lock = lock
+    set.rlock = rlock
+    set.set = inner
+    return set, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses NumBuckets and AtomicSize, with a 'sync.Mutex'
+    lock := new(sync.Mutex)
+    return new_set[K, V](opts, lock, lock)
+}
+
+func NewRW[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses NumBuckets and AtomicSize, with a 'sync.RWMutex'
+    lock := new(sync.RWMutex)
+    return new_set[K, V](opts, lock, lock.RLocker())
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Size()
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Find(key)
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    return set.set.Insert(key, val)
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    return set.set.Delete(key)
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Snapshot()
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    return set.set.BulkLoad(pairs)
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    return set.set.Put(key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    return set.set.CompareAndSwap(key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    return set.set.LoadOrStore(key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    return set.set.Compute(key, fn)
+}
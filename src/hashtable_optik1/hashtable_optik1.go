@@ -0,0 +1,293 @@
+/**
+ * @file   hashtable_optik1.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Using one lazy list per bucket
+**/
+
+package hashtable_optik1
+
+import (
+    "cmp"
+    "runtime"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/optik"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    read_only_fail bool = true
+    maxhtlength uint = 65536 // # of buckets
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    next *node[K, V]
+}
+
+type bucket[K cmp.Ordered, V any] struct {
+    head *node[K, V]
+    lock optik.Mutex
+}
+
+type DataSet[K cmp.Ordered, V any] struct { // Keys are ordered, as each bucket is kept sorted
+    hash uint
+    hasher share.Hasher[K]
+    buckets []bucket[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (bckt *bucket[K, V]) init() {
+    bckt.head = nil
+    bckt.lock.Init()
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V]) *node[K, V] {
+    nd := new(node[K, V])
+    nd.key = key
+    nd.val = val
+    nd.next = next
+    return nd
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    bucket := &set.buckets[set.hasher(key) & set.hash]
+
+    for {
+        pred_ver := bucket.lock.Load()
+        var pred *node[K, V] = nil
+        curr := bucket.head
+        for curr != nil && curr.key < key {
+            pred = curr
+            curr = curr.next
+        }
+        found := curr != nil && curr.key == key
+        var cur V
+        if found {
+            cur = curr.val
+        }
+
+        val, action := fn(cur, found)
+        if action == share.ACTION_KEEP || (action == share.ACTION_DELETE && !found) { // Read-only, like 'Find'
+            return cur, found
+        }
+        if !bucket.lock.TryLock_version(pred_ver) {
+            runtime.Gosched() // In order not to fight against the GC
+            continue
+        }
+
+        next := curr
+        if found {
+            next = curr.next
+        }
+        if action == share.ACTION_STORE { // Nodes are immutable but for their successor, so a value is replaced by a new node
+            next = new_node(key, val, next)
+        }
+        if pred != nil {
+            pred.next = next
+        } else {
+            bucket.head = next
+        }
+        bucket.lock.Unlock()
+
+        if action == share.ACTION_STORE {
+            if !found {
+                set.count.Add(1)
+            }
+            return val, true
+        }
+        set.count.Add(-1)
+        var zero V
+        return zero, false
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize, fixed amount of buckets
+    set := new(DataSet[K, V])
+    set.hash = maxhtlength - 1
+    set.hasher = share.NewHasher[K]()
+    set.buckets = make([]bucket[K, V], maxhtlength)
+    for i := uint(0); i < maxhtlength; i++ {
+        set.buckets[i].init()
+    }
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    for i := uint(0); i < maxhtlength; i++ {
+        node := set.buckets[i].head
+        for node != nil {
+            size++
+            node = node.next
+        }
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    curr := set.buckets[set.hasher(key) & set.hash].head
+    for curr != nil && curr.key < key {
+        curr = curr.next
+    }
+    if curr != nil && curr.key == key {
+        return curr.val, true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    bucket := &set.buckets[set.hasher(key) & set.hash]
+
+    var curr, pred *node[K, V]
+    for {
+        pred_ver := bucket.lock.Load()
+        pred = nil
+        curr = bucket.head
+        for curr != nil && curr.key < key {
+            pred = curr
+            curr = curr.next
+        }
+        if curr != nil && curr.key == key {
+            return false
+        }
+        if bucket.lock.TryLock_version(pred_ver) {
+            break
+        }
+        runtime.Gosched() // In order not to fight against the GC
+    }
+
+    newnode := new_node(key, val, curr)
+    if pred != nil {
+        pred.next = newnode
+    } else {
+        bucket.head = newnode
+    }
+    bucket.lock.Unlock()
+    set.count.Add(1)
+
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    bucket := &set.buckets[set.hasher(key) & set.hash]
+
+    var curr, pred *node[K, V]
+    for {
+        pred_ver := bucket.lock.Load()
+        pred = nil
+        curr = bucket.head
+        for curr != nil && curr.key < key {
+            pred = curr
+            curr = curr.next
+        }
+        if curr == nil || curr.key != key {
+            var zero V
+            return zero, false
+        }
+        if bucket.lock.TryLock_version(pred_ver) {
+            break
+        }
+        runtime.Gosched() // In order not to fight against the GC
+    }
+
+    result := curr.val
+    if pred != nil {
+        pred.next = curr.next
+    } else {
+        bucket.head = curr.next
+    }
+    bucket.lock.Unlock()
+    set.count.Add(-1)
+
+    return result, true
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Lock every bucket in order, so that every concurrent update fails its validation
+    for i := uint(0); i < maxhtlength; i++ {
+        set.buckets[i].lock.Lock()
+    }
+    var res []dataset.Pair[K, V]
+    for i := uint(0); i < maxhtlength; i++ {
+        for curr := set.buckets[i].head; curr != nil; curr = curr.next {
+            res = append(res, dataset.Pair[K, V]{Key: curr.key, Val: curr.val})
+        }
+        set.buckets[i].lock.Unlock()
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: append the sorted elements to their bucket, which thus stays sorted
+    if set.Size() != 0 {
+        return share.Invalid_load("hashtable_optik1", "the set is not empty")
+    }
+    if err := share.Check_sorted("hashtable_optik1", pairs); err != nil {
+        return err
+    }
+    tails := make([]*node[K, V], len(set.buckets)) // Last node of each bucket
+    for _, pair := range pairs {
+        b := set.hasher(pair.Key) & set.hash
+        nd := new_node(pair.Key, pair.Val, nil)
+        if tails[b] == nil {
+            set.buckets[b].head = nd
+        } else {
+            tails[b].next = nd
+        }
+        tails[b] = nd
+    }
+    set.count.Add(int64(len(pairs)))
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
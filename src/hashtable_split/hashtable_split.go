@@ -0,0 +1,408 @@
+/**
+ * @file   hashtable_split.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Split-Ordered Lists: Lock-Free Extensible Hash Tables,
+ * Ori Shalev, Nir Shavit,
+ * Journal of the ACM 2006.
+ *
+ * Every element is stored in a single Harris list (as 'linkedlist_harris_opt'),
+ * sorted by the bit-reversal of its hash, so that each bucket is a contiguous
+ * part of the list starting at a dummy node. Doubling the amount of buckets
+ * only doubles a counter: each new bucket gets initialized by the first thread
+ * accessing it, which inserts its dummy node after the dummy node of its parent
+ * bucket (the same index without its highest bit). The buckets are reached
+ * through segments of increasing size allocated on demand, so that they never
+ * get copied. Keys of equal hash, not being ordered, are kept in insertion order.
+**/
+
+package hashtable_split
+
+import (
+    "cmp"
+    "math/bits"
+    "slices"
+    "sync/atomic"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    max_load = 2       // Average amount of elements per bucket triggering the doubling of the buckets
+    check_period = 64  // Amount of insertions counted on a stripe between two checks of the load (power of 2)
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K comparable, V any] struct {
+    so uint64             // Split-order key: bit-reversed hash with the lowest bit set, or bit-reversed bucket index for a dummy node
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced, nil once deleted (and for a dummy node)
+    next *node[K, V]      // Nil at the end of the list
+}
+
+// Slots of the buckets [2^(s - 1), 2^s) for the segment s > 0, of the bucket 0 for the segment 0
+type segment[K comparable, V any] []atomic.Pointer[node[K, V]]
+
+type DataSet[K comparable, V any] struct {
+    hasher share.Hasher[K]
+    segments [bits.UintSize]atomic.Pointer[segment[K, V]] // Allocated on demand
+    size atomic.Uint64                                    // Amount of buckets, a power of 2
+    count *counter.Striped                                // Amount of elements, always maintained to decide when to double the buckets
+}
+
+// -----------------------------------------------------------------------------
+
+func is_marked_ref[K comparable, V any](i *node[K, V]) bool {
+    return (uintptr(unsafe.Pointer(i)) & 1) != 0
+}
+
+func get_unmarked_ref[K comparable, V any](w *node[K, V]) *node[K, V] {
+    if !is_marked_ref(w) {
+        return w
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(w), -1)) // Stay in unsafe.Add, so the GC keeps seeing a pointer
+}
+
+func get_marked_ref[K comparable, V any](w *node[K, V]) *node[K, V] {
+    if is_marked_ref(w) {
+        return w
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(w), 1))
+}
+
+func cas_next[K comparable, V any](n *node[K, V], old *node[K, V], new *node[K, V]) bool {
+    return atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&n.next)), unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+func mark_next[K comparable, V any](n *node[K, V]) { // Mark the next pointer of 'n', so that nothing gets inserted after it
+    for {
+        next := n.next
+        if is_marked_ref(next) || cas_next(n, next, get_marked_ref(next)) {
+            return
+        }
+    }
+}
+
+func physical_delete_right[K comparable, V any](left_node *node[K, V], right_node *node[K, V]) bool { // 'right_node' must be marked
+    return cas_next(left_node, right_node, get_unmarked_ref(right_node.next))
+}
+
+// -----------------------------------------------------------------------------
+
+func so_regular(hash uint) uint64 {
+    return bits.Reverse64(uint64(hash) | 1 << 63)
+}
+
+func so_dummy(bucket uint) uint64 {
+    return bits.Reverse64(uint64(bucket))
+}
+
+func parent(bucket uint) uint { // Bucket split to create 'bucket' (non-null)
+    return bucket &^ (1 << (bits.Len(bucket) - 1))
+}
+
+func (n *node[K, V]) before(so uint64, key K) bool { // Whether 'n' precedes the position of ('so', 'key'), elements of equal hash being skipped
+    return n.so < so || (n.so == so && so & 1 != 0 && n.key != key)
+}
+
+func (n *node[K, V]) equal(so uint64, key K) bool {
+    return n != nil && n.so == so && so & 1 != 0 && n.key == key
+}
+
+func (n *node[K, V]) value() *V { // Current value, nil if logically deleted
+    return n.ref.Load()
+}
+
+func new_node[K comparable, V any](so uint64, key K, val V, next *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.so = so
+    node.key = key
+    node.val = val
+    node.ref.Store(&node.val)
+    node.next = next
+    return node
+}
+
+func list_search[K comparable, V any](left_node *node[K, V], so uint64, key K) (*node[K, V], *node[K, V]) { // From a dummy node preceding the position, nil 'right_node' at the end of the list
+    right_node := get_unmarked_ref(left_node.next)
+    for right_node != nil {
+        if !is_marked_ref(right_node.next) {
+            if !right_node.before(so, key) {
+                break
+            }
+            left_node = right_node
+        } else {
+            physical_delete_right(left_node, right_node)
+        }
+        right_node = get_unmarked_ref(right_node.next)
+    }
+    return left_node, right_node
+}
+
+func (set *DataSet[K, V]) slot(bucket uint) *atomic.Pointer[node[K, V]] { // Slot of the dummy node of 'bucket', allocating its segment if needed
+    s := bits.Len(bucket)
+    base := uint(1) << s >> 1
+    seg := set.segments[s].Load()
+    if seg == nil {
+        fresh := make(segment[K, V], max(1, base))
+        if set.segments[s].CompareAndSwap(nil, &fresh) {
+            seg = &fresh
+        } else {
+            seg = set.segments[s].Load()
+        }
+    }
+    return &(*seg)[bucket - base]
+}
+
+func (set *DataSet[K, V]) get_bucket(bucket uint) *node[K, V] { // Dummy node of 'bucket', initializing the bucket if needed
+    slot := set.slot(bucket)
+    if dummy := slot.Load(); dummy != nil {
+        return dummy
+    }
+    start := set.get_bucket(parent(bucket)) // The bucket 0 is initialized by 'New'
+    var key K
+    var val V
+    dummy := new_node(so_dummy(bucket), key, val, nil)
+    dummy.ref.Store(nil)
+    for {
+        left_node, right_node := list_search(start, dummy.so, key)
+        if right_node != nil && right_node.so == dummy.so { // Initialized concurrently
+            dummy = right_node
+            break
+        }
+        dummy.next = right_node
+        if cas_next(left_node, right_node, dummy) {
+            break
+        }
+    }
+    slot.Store(dummy) // Every thread initializing the bucket stores the same node
+    return dummy
+}
+
+func (set *DataSet[K, V]) locate(key K) (*node[K, V], uint64) { // Dummy node of the bucket of 'key', and split-order key of 'key'
+    hash := set.hasher(key)
+    return set.get_bucket(hash & uint(set.size.Load() - 1)), so_regular(hash)
+}
+
+func (set *DataSet[K, V]) inserted() { // Account for a new element, doubling the buckets if too loaded (only checked when its stripe reaches a multiple of 'check_period', as the sum reads every stripe)
+    if set.count.Add(1) & (check_period - 1) != 0 {
+        return
+    }
+    for {
+        size := set.size.Load()
+        if set.count.Sum() <= int64(max_load * size) || size >= 1 << (bits.UintSize - 1) {
+            return
+        }
+        set.size.CompareAndSwap(size, 2 * size)
+    }
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    start, so := set.locate(key)
+    for {
+        left_node, right_node := list_search(start, so, key)
+        if !right_node.equal(so, key) {
+            var zero V
+            val, action := fn(zero, false)
+            if action != share.ACTION_STORE {
+                return zero, false
+            }
+            if cas_next(left_node, right_node, new_node(so, key, val, right_node)) {
+                set.inserted()
+                return val, true
+            }
+            continue
+        }
+        p := right_node.value()
+        if p == nil { // Being deleted, help before retrying
+            mark_next(right_node)
+            physical_delete_right(left_node, right_node)
+            continue
+        }
+        val, action := fn(*p, true)
+        switch action {
+        case share.ACTION_STORE:
+            if right_node.ref.CompareAndSwap(p, &val) {
+                return val, true
+            }
+        case share.ACTION_DELETE:
+            if right_node.ref.CompareAndSwap(p, nil) {
+                set.count.Add(-1)
+                mark_next(right_node)
+                physical_delete_right(left_node, right_node)
+                var zero V
+                return zero, false
+            }
+        default:
+            return *p, true
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K comparable, V any](opts share.Options) (*DataSet[K, V], error) { // Uses NumBuckets (initial amount), the elements being always counted
+    num_buckets := share.Or_default(opts.NumBuckets, share.DEFAULT_NUM_BUCKETS)
+    if !share.Is_pow2(num_buckets) {
+        return nil, share.Invalid_option("hashtable_split", "amount of buckets", num_buckets, "a power of 2")
+    }
+    set := new(DataSet[K, V])
+    set.hasher = share.NewHasher[K]()
+    set.size.Store(uint64(num_buckets))
+    var key K
+    var val V
+    head := new_node(so_dummy(0), key, val, nil)
+    head.ref.Store(nil)
+    set.slot(0).Store(head)
+    set.count = counter.New()
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    return set.count.Size()
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    start, so := set.locate(key)
+    node := get_unmarked_ref(start.next)
+    for node != nil && node.before(so, key) {
+        node = get_unmarked_ref(node.next)
+    }
+    if node.equal(so, key) {
+        if p := node.value(); p != nil {
+            return *p, true
+        }
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    start, so := set.locate(key)
+    for {
+        left_node, right_node := list_search(start, so, key)
+        if right_node.equal(so, key) {
+            if right_node.value() != nil {
+                return false
+            }
+            mark_next(right_node) // Being deleted, help before retrying
+            physical_delete_right(left_node, right_node)
+            continue
+        }
+        if cas_next(left_node, right_node, new_node(so, key, val, right_node)) {
+            set.inserted()
+            return true
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (result V, ok bool) {
+    start, so := set.locate(key)
+    var left_node *node[K, V]
+    var right_node *node[K, V]
+    var p *V
+    for {
+        left_node, right_node = list_search(start, so, key)
+        if !right_node.equal(so, key) {
+            return
+        }
+        p = right_node.value()
+        if p == nil { // Already being deleted
+            return
+        }
+        if right_node.ref.CompareAndSwap(p, nil) { // Try to clear right_node's value, i.e. to delete it logically
+            break
+        }
+    }
+    result, ok = *p, true
+    set.count.Add(-1)
+    mark_next(right_node)
+    physical_delete_right(left_node, right_node)
+    return
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: size the buckets for the elements, then link every node in split order
+    if set.Size() != 0 {
+        return share.Invalid_load("hashtable_split", "the set is not empty")
+    }
+    size := set.size.Load()
+    for max_load * size < uint64(len(pairs)) {
+        size *= 2
+    }
+    nodes := make([]*node[K, V], 0, len(pairs) + int(size))
+    for _, pair := range pairs {
+        nodes = append(nodes, new_node(so_regular(set.hasher(pair.Key)), pair.Key, pair.Val, nil))
+    }
+    for bucket := uint(1); bucket < uint(size); bucket++ { // The bucket 0 is the head of the list
+        var key K
+        var val V
+        dummy := new_node(so_dummy(bucket), key, val, nil)
+        dummy.ref.Store(nil)
+        set.slot(bucket).Store(dummy)
+        nodes = append(nodes, dummy)
+    }
+    slices.SortStableFunc(nodes, func(a *node[K, V], b *node[K, V]) int { // Stable, so that the first of repeated keys comes first
+        return cmp.Compare(a.so, b.so)
+    })
+    var loaded int64 = 0
+    prev := set.slot(0).Load()
+    for i, node := range nodes {
+        repeated := false
+        for j := i - 1; j >= 0 && nodes[j].so == node.so; j-- { // Among the elements of equal hash
+            repeated = repeated || nodes[j].equal(node.so, node.key)
+        }
+        if repeated {
+            continue
+        }
+        prev.next = node
+        prev = node
+        if node.so & 1 != 0 {
+            loaded++
+        }
+    }
+    set.size.Store(size)
+    set.count.Add(loaded)
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
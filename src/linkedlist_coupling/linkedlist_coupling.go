@@ -0,0 +1,322 @@
+/**
+ * @file   linkedlist_coupling.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Concurrency of Operations on B-Trees,
+ * Rudolf Bayer, Mario Schkolnick,
+ * Acta Informatica 1977.
+ *
+ * Lock coupling (hand-over-hand locking): every operation on a key, searches
+ * included, locks the successor before releasing the predecessor, so that it
+ * ends up holding the two nodes around the key. The scans hold one node at a
+ * time instead, and never call back with a node locked.
+**/
+
+package linkedlist_coupling
+
+import (
+    "cmp"
+    "iter"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    bound share.Bound
+    next *node[K, V]
+    marked bool // Unlinked, so that the scans skip it
+    mutex ttas.Mutex
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    head *node[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) lock() {
+    n.mutex.Lock()
+}
+
+func (n *node[K, V]) unlock() {
+    n.mutex.Unlock()
+}
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) read() (V, bool, *node[K, V]) { // Value (and whether still linked) and successor, read under the lock of 'n'
+    n.lock()
+    defer n.unlock()
+    return n.val, !n.marked, n.next
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.key = key
+    node.val = val
+    node.next = next
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V]) *node[K, V] {
+    var key K
+    var val V
+    node := new_node(key, val, next)
+    node.bound = bound
+    return node
+}
+
+func (set *DataSet[K, V]) locate(key K) (pred *node[K, V], curr *node[K, V]) { // First node not less than 'key' and its predecessor, both locked hand-over-hand
+    pred = set.head
+    pred.lock()
+    curr = pred.next
+    curr.lock()
+    for curr.less(key) {
+        pred.unlock()
+        pred = curr
+        curr = curr.next
+        curr.lock()
+    }
+    return
+}
+
+func scan[K cmp.Ordered, V any](node *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the linked nodes from 'node' to the tail, until it returns false
+    for node.bound != share.BOUND_MAX {
+        val, ok, next := node.read()
+        if ok && !fn(node.key, val) {
+            return
+        }
+        node = next // The successor of an unlinked node stays valid, and leads back to the list
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of key not less than 'key' (greater if 'strict'), linked or not
+    _, _, curr := set.head.read()
+    for curr.less(key) || (strict && curr.equal(key)) {
+        _, _, curr = curr.read()
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) (K, V, bool) { // First linked node from 'curr'
+    for curr.bound != share.BOUND_MAX {
+        val, ok, next := curr.read()
+        if ok {
+            return curr.key, val, true
+        }
+        curr = next
+    }
+    var key K
+    var val V
+    return key, val, false
+}
+
+func (set *DataSet[K, V]) last(in func(key K) bool) (K, V, bool) { // Last linked node among the first nodes satisfying 'in'
+    var res K
+    var res_val V
+    found := false
+    _, _, curr := set.head.read()
+    for curr.bound != share.BOUND_MAX && in(curr.key) {
+        val, ok, next := curr.read()
+        if ok {
+            res, res_val, found = curr.key, val, true
+        }
+        curr = next
+    }
+    return res, res_val, found
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    pred, curr := set.locate(key)
+    defer pred.unlock()
+    defer curr.unlock()
+    found := curr.equal(key)
+    var cur V
+    if found {
+        cur = curr.val
+    }
+    val, action := fn(cur, found)
+    switch {
+    case action == share.ACTION_STORE && found:
+        curr.val = val
+    case action == share.ACTION_STORE:
+        pred.next = new_node(key, val, curr)
+        set.count.Add(1)
+    case action == share.ACTION_DELETE && found:
+        curr.marked = true
+        pred.next = curr.next
+        set.count.Add(-1)
+    default:
+        return cur, found
+    }
+    if action == share.ACTION_DELETE {
+        var zero V
+        return zero, false
+    }
+    return val, true
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    max := new_sentinel[K, V](share.BOUND_MAX, nil)
+    min := new_sentinel(share.BOUND_MIN, max)
+    set.head = min
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    for range set.All() {
+        size++
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    pred, curr := set.locate(key)
+    defer pred.unlock()
+    defer curr.unlock()
+    if curr.equal(key) {
+        return curr.val, true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    pred, curr := set.locate(key)
+    defer pred.unlock()
+    defer curr.unlock()
+    if curr.equal(key) {
+        return false
+    }
+    pred.next = new_node(key, val, curr)
+    set.count.Add(1)
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    pred, curr := set.locate(key)
+    defer pred.unlock()
+    defer curr.unlock()
+    if !curr.equal(key) {
+        var zero V
+        return zero, false
+    }
+    curr.marked = true
+    pred.next = curr.next
+    set.count.Add(-1)
+    return curr.val, true
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Lock every node hand-over-hand from the head, keeping them locked until the tail is reached
+    var res []dataset.Pair[K, V]
+    set.head.lock()
+    curr := set.head.next
+    for curr.bound != share.BOUND_MAX {
+        curr.lock()
+        res = append(res, dataset.Pair[K, V]{Key: curr.key, Val: curr.val})
+        curr = curr.next
+    }
+    for node := set.head; node != curr; node = node.next {
+        node.unlock()
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent, one node being locked at a time
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        _, _, curr := set.head.read()
+        scan(curr, yield)
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    _, _, curr := set.head.read()
+    return first(curr)
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return set.last(func(key K) bool { return true })
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return first(set.search(key, false))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return set.last(func(k K) bool { return k <= key })
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return first(set.search(key, true))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return set.last(func(k K) bool { return k < key })
+}
@@ -0,0 +1,370 @@
+/**
+ * @file   linkedlist_harris_opt.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * A Pragmatic Implementation of Non-blocking Linked Lists,
+ * Timothy L Harris,
+ * DISC 2001.
+ *
+ * Optimized version
+ *
+ * Values are boxed behind an atomic pointer, so that they can be replaced with
+ * a CAS; a node is logically deleted once this pointer is cleared, before its
+ * next pointer gets marked.
+**/
+
+package linkedlist_harris_opt
+
+import (
+    "cmp"
+    "iter"
+    "sync/atomic"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced, nil once deleted
+    bound share.Bound
+    next *node[K, V]
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    head *node[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func is_marked_ref[K cmp.Ordered, V any](i *node[K, V]) bool {
+    return (uintptr(unsafe.Pointer(i)) & 1) != 0
+}
+
+func get_unmarked_ref[K cmp.Ordered, V any](w *node[K, V]) *node[K, V] {
+    if !is_marked_ref(w) {
+        return w
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(w), -1)) // Stay in unsafe.Add, so the GC keeps seeing a pointer
+}
+
+func get_marked_ref[K cmp.Ordered, V any](w *node[K, V]) *node[K, V] {
+    if is_marked_ref(w) {
+        return w
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(w), 1))
+}
+
+func mark_next[K cmp.Ordered, V any](n *node[K, V]) { // Mark the next pointer of 'n', so that nothing gets inserted after it
+    for {
+        next := n.next
+        if is_marked_ref(next) || atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&n.next)), unsafe.Pointer(next), unsafe.Pointer(get_marked_ref(next))) {
+            return
+        }
+    }
+}
+
+func physical_delete_right[K cmp.Ordered, V any](left_node *node[K, V], right_node *node[K, V]) bool { // 'right_node' must be marked
+    return atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&left_node.next)), unsafe.Pointer(right_node), unsafe.Pointer(get_unmarked_ref(right_node.next)))
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) value() *V { // Current value, nil if logically deleted
+    return n.ref.Load()
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.key = key
+    node.val = val
+    node.ref.Store(&node.val)
+    node.next = next
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V]) *node[K, V] {
+    var key K
+    var val V
+    node := new_node(key, val, next)
+    node.bound = bound
+    return node
+}
+
+func list_search[K cmp.Ordered, V any](set *DataSet[K, V], key K) (left_node *node[K, V], right_node *node[K, V]) {
+    left_node = set.head
+    right_node = left_node.next
+    for {
+        if !is_marked_ref(right_node.next) {
+            if !right_node.less(key) {
+                return
+            }
+            left_node = right_node
+        } else {
+            physical_delete_right(left_node, right_node)
+        }
+        right_node = get_unmarked_ref(right_node.next)
+    }
+}
+
+func scan[K cmp.Ordered, V any](node *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the unmarked nodes from 'node' to the tail, until it returns false
+    for node.bound != share.BOUND_MAX {
+        if p := node.value(); p != nil && !fn(node.key, *p) {
+            return
+        }
+        node = get_unmarked_ref(node.next)
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of key not less than 'key' (greater if 'strict'), deleted or not
+    curr := get_unmarked_ref(set.head.next)
+    for curr.less(key) || (strict && curr.equal(key)) {
+        curr = get_unmarked_ref(curr.next)
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) (*node[K, V], *V) { // First live node from 'curr' and its value, nil if none
+    for curr.bound != share.BOUND_MAX {
+        if p := curr.value(); p != nil {
+            return curr, p
+        }
+        curr = get_unmarked_ref(curr.next)
+    }
+    return nil, nil
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) (*node[K, V], *V) { // Last live node among the first nodes satisfying 'in' and its value, nil if none
+    var res *node[K, V] = nil
+    var res_val *V = nil
+    curr := get_unmarked_ref(set.head.next)
+    for curr.bound != share.BOUND_MAX && in(curr) {
+        if p := curr.value(); p != nil {
+            res, res_val = curr, p
+        }
+        curr = get_unmarked_ref(curr.next)
+    }
+    return res, res_val
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V], p *V) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, *p, true
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    for {
+        left_node, right_node := list_search(set, key)
+        if !right_node.equal(key) {
+            var zero V
+            val, action := fn(zero, false)
+            if action != share.ACTION_STORE {
+                return zero, false
+            }
+            node_add := new_node(key, val, right_node)
+            if atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&left_node.next)), unsafe.Pointer(right_node), unsafe.Pointer(node_add)) {
+                set.count.Add(1)
+                return val, true
+            }
+            continue
+        }
+        p := right_node.value()
+        if p == nil { // Being deleted, help before retrying
+            mark_next(right_node)
+            physical_delete_right(left_node, right_node)
+            continue
+        }
+        val, action := fn(*p, true)
+        switch action {
+        case share.ACTION_STORE:
+            if right_node.ref.CompareAndSwap(p, &val) {
+                return val, true
+            }
+        case share.ACTION_DELETE:
+            if right_node.ref.CompareAndSwap(p, nil) {
+                set.count.Add(-1)
+                mark_next(right_node)
+                physical_delete_right(left_node, right_node)
+                var zero V
+                return zero, false
+            }
+        default:
+            return *p, true
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    max := new_sentinel[K, V](share.BOUND_MAX, nil)
+    min := new_sentinel(share.BOUND_MIN, max)
+    set.head = min
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    node := get_unmarked_ref(set.head.next) // We have at least 2 elements
+    for get_unmarked_ref(node.next) != nil {
+        if node.value() != nil {
+            size++
+        }
+        node = get_unmarked_ref(node.next)
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    node := set.head.next
+    for node.less(key) {
+        node = get_unmarked_ref(node.next)
+    }
+    if node.equal(key) {
+        if p := node.value(); p != nil {
+            return *p, true
+        }
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    for {
+        left_node, right_node := list_search(set, key)
+        if right_node.equal(key) {
+            if right_node.value() != nil {
+                return false
+            }
+            mark_next(right_node) // Being deleted, help before retrying
+            physical_delete_right(left_node, right_node)
+            continue
+        }
+        node_add := new_node(key, val, right_node)
+        if atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&left_node.next)), unsafe.Pointer(right_node), unsafe.Pointer(node_add)) { // Try to swing left_node's unmarked next pointer to a new node
+            set.count.Add(1)
+            return true
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (result V, ok bool) {
+    var left_node *node[K, V]
+    var right_node *node[K, V]
+    var p *V
+    for {
+        left_node, right_node = list_search(set, key)
+        if !right_node.equal(key) {
+            return
+        }
+        p = right_node.value()
+        if p == nil { // Already being deleted
+            return
+        }
+        if right_node.ref.CompareAndSwap(p, nil) { // Try to clear right_node's value, i.e. to delete it logically
+            break
+        }
+    }
+    result, ok = *p, true
+    set.count.Add(-1)
+    mark_next(right_node)
+    physical_delete_right(left_node, right_node)
+    return
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent, marked nodes being skipped (but not unlinked)
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        scan(set.head.next, yield)
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(get_unmarked_ref(set.head.next)))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
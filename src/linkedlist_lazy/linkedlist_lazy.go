@@ -0,0 +1,390 @@
+/**
+ * @file   linkedlist_lazy.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * A Lazy Concurrent List-Based Set Algorithm,
+ * S. Heller, M. Herlihy, V. Luchangco, M. Moir, W.N. Scherer III, N. Shavit
+ * p.3-16, OPODIS 2005
+**/
+
+package linkedlist_lazy
+
+import (
+    "cmp"
+    "iter"
+    "sync/atomic"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
+)
+
+const (
+    lazy_ro_fail = true
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced
+    bound share.Bound
+    next *node[K, V]
+    marked bool
+    mutex ttas.Mutex
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    head *node[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) lock() {
+    n.mutex.Lock()
+}
+
+func (n *node[K, V]) unlock() {
+    n.mutex.Unlock()
+}
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) value() *V { // Current value, nil if logically deleted
+    if n.marked {
+        return nil
+    }
+    return n.ref.Load()
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V]) *node[K, V] {
+    node := new(node[K, V]) // No allocation failure test to do, and we cannot recover from an "OOM panic" (see http://stackoverflow.com/questions/30577308/golang-cannot-recover-from-out-of-memory-crash)
+    node.key = key
+    node.val = val
+    node.ref.Store(&node.val)
+    node.next = next
+    node.marked = false
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V]) *node[K, V] {
+    var key K
+    var val V
+    node := new_node(key, val, next)
+    node.bound = bound
+    return node
+}
+
+func validate[K cmp.Ordered, V any](pred *node[K, V], curr *node[K, V]) bool {
+    return !pred.marked && !curr.marked && pred.next == curr
+}
+
+func scan[K cmp.Ordered, V any](node *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the unmarked nodes from 'node' to the tail, until it returns false
+    for node.bound != share.BOUND_MAX {
+        if p := node.value(); p != nil && !fn(node.key, *p) {
+            return
+        }
+        node = node.next
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of key not less than 'key' (greater if 'strict'), deleted or not
+    curr := set.head.next
+    for curr.less(key) || (strict && curr.equal(key)) {
+        curr = curr.next
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) (*node[K, V], *V) { // First live node from 'curr' and its value, nil if none
+    for curr.bound != share.BOUND_MAX {
+        if p := curr.value(); p != nil {
+            return curr, p
+        }
+        curr = curr.next
+    }
+    return nil, nil
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) (*node[K, V], *V) { // Last live node among the first nodes satisfying 'in' and its value, nil if none
+    var res *node[K, V] = nil
+    var res_val *V = nil
+    curr := set.head.next
+    for curr.bound != share.BOUND_MAX && in(curr) {
+        if p := curr.value(); p != nil {
+            res, res_val = curr, p
+        }
+        curr = curr.next
+    }
+    return res, res_val
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V], p *V) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, *p, true
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    for {
+        pred := set.head
+        curr := pred.next
+        for curr.less(key) {
+            pred = curr
+            curr = curr.next
+        }
+        pred.lock()
+        curr.lock()
+        if !validate(pred, curr) {
+            curr.unlock()
+            pred.unlock()
+            continue
+        }
+        found := curr.equal(key)
+        var cur V
+        if found {
+            cur = *curr.ref.Load()
+        }
+        val, action := fn(cur, found)
+        switch {
+        case action == share.ACTION_STORE && found:
+            curr.ref.Store(&val)
+        case action == share.ACTION_STORE:
+            newnode := new_node(key, val, curr)
+            atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&pred.next)), unsafe.Pointer(newnode))
+            set.count.Add(1)
+        case action == share.ACTION_DELETE && found:
+            curr.marked = true
+            pred.next = curr.next
+            set.count.Add(-1)
+        default:
+            val = cur
+        }
+        curr.unlock()
+        pred.unlock()
+        if action == share.ACTION_DELETE {
+            var zero V
+            return zero, false
+        }
+        return val, found || action == share.ACTION_STORE
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    max := new_sentinel[K, V](share.BOUND_MAX, nil)
+    min := new_sentinel(share.BOUND_MIN, max)
+    set.head = min
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint
+    var node *node[K, V]
+    /* We have at least 2 elements */
+    node = set.head.next
+    for node.next != nil {
+        size++
+        node = node.next
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    curr := set.head
+    for curr.less(key) {
+        curr = curr.next
+    }
+    if curr.equal(key) {
+        if p := curr.value(); p != nil {
+            return *p, true
+        }
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var curr *node[K, V]
+    var pred *node[K, V]
+    var newnode *node[K, V]
+    for {
+        // PARSE_TRY()
+        pred = set.head
+        curr = pred.next
+        for curr.less(key) {
+            pred = curr
+            curr = curr.next
+        }
+        // UPDATE_TRY()
+        if lazy_ro_fail {
+            if curr.equal(key) {
+                if curr.marked {
+                    continue
+                }
+                return false
+            }
+        }
+        {
+            pred.lock()
+            if validate(pred, curr) {
+                if curr.equal(key) {
+                    pred.unlock()
+                    return false
+                } else {
+                    newnode = new_node(key, val, curr)
+                    atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&pred.next)), unsafe.Pointer(newnode))
+                    pred.unlock()
+                    set.count.Add(1)
+                    return true
+                }
+            }
+            pred.unlock()
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (result V, ok bool) {
+    var pred *node[K, V]
+    var curr *node[K, V]
+    var done bool = false
+    for !done {
+        pred = set.head
+        curr = pred.next
+        for curr.less(key) {
+            pred = curr
+            curr = curr.next
+        }
+        if lazy_ro_fail {
+            if !curr.equal(key) {
+                return
+            }
+        }
+        {
+            pred.lock()
+            curr.lock()
+            if validate(pred, curr) {
+                if curr.equal(key) {
+                    result, ok = *curr.ref.Load(), true
+                    var c_nxt *node[K, V] = curr.next
+                    curr.marked = true
+                    pred.next = c_nxt
+                    set.count.Add(-1)
+                }
+                done = true
+            }
+            curr.unlock()
+            pred.unlock()
+        }
+    }
+    return
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Lock every node hand-over-hand from the head, keeping them locked until the tail is reached
+    var res []dataset.Pair[K, V]
+    set.head.lock()
+    curr := set.head.next
+    for curr.bound != share.BOUND_MAX {
+        curr.lock()
+        res = append(res, dataset.Pair[K, V]{Key: curr.key, Val: *curr.ref.Load()})
+        curr = curr.next
+    }
+    for node := set.head; node != curr; node = node.next {
+        node.unlock()
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent and wait-free, like 'Find'
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        scan(set.head.next, yield)
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(set.head.next))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
@@ -0,0 +1,476 @@
+/**
+ * @file   linkedlist_optik_cache.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * A Lazy Concurrent List-Based Set Algorithm,
+ * S. Heller, M. Herlihy, V. Luchangco, M. Moir, W.N. Scherer III, N. Shavit
+ * p.3-16, OPODIS 2005
+ *
+ * OPTIK linked list with a node cache: each operation on a key remembers the
+ * predecessor it reached, and the next operation (of the same processor)
+ * starts from this node instead of the head if it precedes the key and is not
+ * locked, i.e. neither deleted nor being modified. The caches are kept in a
+ * 'sync.Pool', whose objects are (mostly) local to each processor, as Go has no
+ * thread-local storage; the garbage collector may empty them at any time.
+**/
+
+package linkedlist_optik_cache
+
+import (
+    "cmp"
+    "iter"
+    "runtime"
+    "sync"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/optik"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced (under the lock)
+    bound share.Bound
+    next *node[K, V]
+    mutex optik.Mutex
+}
+
+type cache[K cmp.Ordered, V any] struct {
+    node *node[K, V] // Last predecessor reached, nil if none
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    head *node[K, V]
+    caches sync.Pool       // Node caches (*cache[K, V])
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.key = key
+    node.val = val
+    node.ref.Store(&node.val)
+    node.next = next
+    node.mutex.Init()
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V]) *node[K, V] {
+    var key K
+    var val V
+    node := new_node(key, val, next)
+    node.bound = bound
+    return node
+}
+
+func (set *DataSet[K, V]) start(key K) (*node[K, V], optik.Mutex, *cache[K, V]) { // Node to start from and its version, i.e. the cached node if it precedes 'key' and is not locked, the head otherwise; and the cache to release
+    c := set.caches.Get().(*cache[K, V])
+    if n := c.node; n != nil && n.less(key) {
+        if ver := n.mutex.Load(); !optik.Is_locked(ver) {
+            return n, ver, c
+        }
+    }
+    return set.head, set.head.mutex.Load(), c
+}
+
+func (set *DataSet[K, V]) release(c *cache[K, V], pred *node[K, V]) { // Remember the predecessor reached, and give the cache back
+    if pred.bound == share.BOUND_NONE {
+        c.node = pred
+    } else {
+        c.node = nil
+    }
+    set.caches.Put(c)
+}
+
+func scan[K cmp.Ordered, V any](node *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the nodes from 'node' to the tail, until it returns false
+    for node.bound != share.BOUND_MAX {
+        if !fn(node.key, *node.ref.Load()) {
+            return
+        }
+        node = node.next
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of key not less than 'key' (greater if 'strict'), deleted or not
+    curr := set.head.next
+    for curr.less(key) || (strict && curr.equal(key)) {
+        curr = curr.next
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) *node[K, V] { // 'curr', nil if the tail
+    if curr.bound == share.BOUND_MAX {
+        return nil
+    }
+    return curr
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) *node[K, V] { // Last node among the first nodes satisfying 'in', nil if none
+    var res *node[K, V] = nil
+    curr := set.head.next
+    for curr.bound != share.BOUND_MAX && in(curr) {
+        res = curr
+        curr = curr.next
+    }
+    return res
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V]) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, *n.ref.Load(), true
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    for {
+        var pred *node[K, V]
+        var pred_ver optik.Mutex
+        curr, curr_ver, c := set.start(key)
+        for {
+            pred = curr
+            pred_ver = curr_ver
+            curr = curr.next
+            curr_ver = curr.mutex.Load()
+            if !curr.less(key) {
+                break
+            }
+        }
+        set.release(c, pred)
+        var zero V
+        if !curr.equal(key) {
+            val, action := fn(zero, false)
+            if action != share.ACTION_STORE {
+                return zero, false
+            }
+            newnode := new_node(key, val, curr)
+            if !pred.mutex.TryLock_version(pred_ver) {
+                continue
+            }
+            pred.next = newnode
+            pred.mutex.Unlock()
+            set.count.Add(1)
+            return val, true
+        }
+        p := curr.ref.Load() // Read after the version, which any replacement changes
+        val, action := fn(*p, true)
+        switch action {
+        case share.ACTION_STORE:
+            if !curr.mutex.TryLock_version(curr_ver) {
+                continue
+            }
+            curr.ref.Store(&val)
+            curr.mutex.Unlock()
+            return val, true
+        case share.ACTION_DELETE:
+            cnxt := curr.next
+            if !pred.mutex.TryLock_version(pred_ver) {
+                continue
+            }
+            if !curr.mutex.TryLock_version(curr_ver) {
+                pred.mutex.Revert()
+                continue
+            }
+            pred.next = cnxt
+            pred.mutex.Unlock()
+            set.count.Add(-1)
+            return zero, false
+        default:
+            return *p, true
+        }
+    }
+}
+
+/** Try once to collect a range atomically: the predecessor of the range and the nodes in the range, each version read before the next pointer and the value, then validated against the versions.
+ * @param lo  Lower bound of the range, nil for none
+ * @param hi  Upper bound of the range, nil for none
+ * @param res Buffer to append the pairs of the range to
+ * @return 'res' with the pairs of the range appended, and whether no node was locked nor modified meanwhile
+**/
+func (set *DataSet[K, V]) try_range_atomic(lo *K, hi *K, res []dataset.Pair[K, V]) ([]dataset.Pair[K, V], bool) {
+    var nodes []*node[K, V]
+    var versions []optik.Mutex
+    pred := set.head
+    pred_ver := pred.mutex.Load()
+    curr := pred.next
+    for lo != nil && curr.less(*lo) {
+        pred = curr
+        pred_ver = pred.mutex.Load()
+        curr = pred.next
+    }
+    if optik.Is_locked(pred_ver) {
+        return res, false
+    }
+    nodes = append(nodes, pred)
+    versions = append(versions, pred_ver)
+    for curr.bound == share.BOUND_NONE && (hi == nil || curr.key <= *hi) {
+        curr_ver := curr.mutex.Load()
+        if optik.Is_locked(curr_ver) { // Being deleted (a deleted node stays locked)
+            return res, false
+        }
+        nodes = append(nodes, curr)
+        versions = append(versions, curr_ver)
+        res = append(res, dataset.Pair[K, V]{Key: curr.key, Val: *curr.ref.Load()})
+        curr = curr.next
+    }
+    // Validate: any insertion in the range locks one of these nodes, any deletion or replacement in the range locks the modified node
+    for i, node := range nodes {
+        if !optik.Is_same_version(versions[i], node.mutex.Load()) {
+            return res, false
+        }
+    }
+    return res, true
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    max := new_sentinel[K, V](share.BOUND_MAX, nil)
+    min := new_sentinel(share.BOUND_MIN, max)
+    set.head = min
+    set.caches.New = func() any { return new(cache[K, V]) }
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    node := set.head.next
+    for node.next != nil {
+        size++
+        node = node.next
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    pred, _, c := set.start(key)
+    curr := pred.next
+    for curr.less(key) {
+        pred = curr
+        curr = curr.next
+    }
+    set.release(c, pred)
+    if curr.equal(key) {
+        return *curr.ref.Load(), true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var pred_ver optik.Mutex
+    for {
+        var pred *node[K, V]
+        curr, curr_ver, c := set.start(key)
+        for {
+            pred = curr
+            pred_ver = curr_ver
+            curr = curr.next
+            if !curr.less(key) {
+                break
+            }
+            curr_ver = curr.mutex.Load()
+        }
+        set.release(c, pred)
+        if curr.equal(key) {
+            return false
+        }
+        newnode := new_node(key, val, curr)
+        if !pred.mutex.TryLock_version(pred_ver) {
+            continue
+        }
+        pred.next = newnode
+        pred.mutex.Unlock()
+        set.count.Add(1)
+        return true
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    for {
+        var pred *node[K, V]
+        var pred_ver optik.Mutex
+        curr, curr_ver, c := set.start(key)
+        for {
+            pred = curr
+            pred_ver = curr_ver
+            curr = curr.next;
+            curr_ver = curr.mutex.Load()
+            if !curr.less(key) {
+                break
+            }
+        }
+        set.release(c, pred)
+        if !curr.equal(key) {
+            var zero V
+            return zero, false
+        }
+        cnxt := curr.next
+        if !pred.mutex.TryLock_version(pred_ver) {
+            continue
+        }
+        if !curr.mutex.TryLock_version(curr_ver) {
+            pred.mutex.Revert()
+            continue
+        }
+        pred.next = cnxt
+        pred.mutex.Unlock()
+        set.count.Add(-1)
+        return *curr.ref.Load(), true
+    }
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Collect the whole list as 'RangeAtomic' does, only locking it after 'share.SNAPSHOT_RETRIES' failed attempts
+    var res []dataset.Pair[K, V]
+    for i := 0; i < share.SNAPSHOT_RETRIES; i++ {
+        var ok bool
+        if res, ok = set.try_range_atomic(nil, nil, res[:0]); ok {
+            return res
+        }
+        runtime.Gosched()
+    }
+    return set.range_locked(nil, nil, res[:0])
+}
+
+/** Collect a range with its nodes locked, locking them hand-over-hand from the head, and keeping them locked from the predecessor of the range to its end.
+ * @param lo  Lower bound of the range, nil for none
+ * @param hi  Upper bound of the range, nil for none
+ * @param res Buffer to append the pairs of the range to
+ * @return 'res' with the pairs of the range appended
+**/
+func (set *DataSet[K, V]) range_locked(lo *K, hi *K, res []dataset.Pair[K, V]) []dataset.Pair[K, V] {
+    pred := set.head
+    pred.mutex.Lock()
+    curr := pred.next
+    for lo != nil && curr.less(*lo) { // The successor of a locked node cannot be deleted, hence is eventually unlocked
+        curr.mutex.Lock()
+        pred.mutex.Unlock()
+        pred = curr
+        curr = pred.next
+    }
+    for curr.bound == share.BOUND_NONE && (hi == nil || curr.key <= *hi) {
+        curr.mutex.Lock()
+        res = append(res, dataset.Pair[K, V]{Key: curr.key, Val: *curr.ref.Load()})
+        curr = curr.next
+    }
+    for node := pred; node != curr; node = node.next {
+        node.mutex.Unlock()
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent, no version being checked
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        scan(set.head.next, yield)
+    }
+}
+
+func (set *DataSet[K, V]) RangeAtomic(lo K, hi K, fn func(key K, val V) bool) { // Only locking the range after 'share.SNAPSHOT_RETRIES' failed attempts
+    var pairs []dataset.Pair[K, V]
+    ok := false
+    for i := 0; i < share.SNAPSHOT_RETRIES && !ok; i++ {
+        if pairs, ok = set.try_range_atomic(&lo, &hi, pairs[:0]); !ok {
+            runtime.Gosched()
+        }
+    }
+    if !ok {
+        pairs = set.range_locked(&lo, &hi, pairs[:0])
+    }
+    for _, pair := range pairs {
+        if !fn(pair.Key, pair.Val) {
+            return
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(set.head.next))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
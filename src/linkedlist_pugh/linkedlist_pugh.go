@@ -0,0 +1,376 @@
+/**
+ * @file   linkedlist_pugh.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * William Pugh.
+ * Concurrent Maintenance of Skip Lists.
+ * Technical report, 1990.
+**/
+
+package linkedlist_pugh
+
+import (
+    "cmp"
+    "iter"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
+)
+
+const (
+    pugh_ro_fail = true
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced (under the lock)
+    bound share.Bound
+    next *node[K, V]
+    mutex ttas.Mutex
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    head *node[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) lock() {
+    n.mutex.Lock()
+}
+
+func (n *node[K, V]) unlock() {
+    n.mutex.Unlock()
+}
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V]) *node[K, V] {
+    elem := new(node[K, V])
+    elem.key = key
+    elem.val = val
+    elem.ref.Store(&elem.val)
+    elem.next = next
+    return elem
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V]) *node[K, V] {
+    var key K
+    var val V
+    elem := new_node(key, val, next)
+    elem.bound = bound
+    return elem
+}
+
+func (set *DataSet[K, V]) search_weak_left(key K) *node[K, V] {
+    pred := set.head
+    succ := pred.next
+    for succ.less(key) {
+        pred = succ
+        succ = succ.next
+    }
+    return pred
+}
+
+func (set *DataSet[K, V]) search_weak_right(key K) *node[K, V] {
+    succ := set.head.next
+    for succ.less(key) {
+        succ = succ.next
+    }
+    return succ
+}
+
+func (set *DataSet[K, V]) search_strong(key K) (pred *node[K, V], succ *node[K, V]) {
+    pred = set.search_weak_left(key)
+    pred.lock()
+    succ = pred.next
+    for succ.less(key) {
+        pred.unlock()
+        pred = succ
+        pred.lock()
+        succ = pred.next
+    }
+    return
+}
+
+func (set *DataSet[K, V]) search_strong_cond(key K, equal bool) (pred *node[K, V], succ *node[K, V], ok bool) {
+    pred = set.search_weak_left(key)
+    succ = pred.next
+    if succ.equal(key) == equal {
+        ok = false
+        return
+    }
+    pred.lock()
+    succ = pred.next
+    for succ.less(key) {
+        pred.unlock()
+        pred = succ
+        pred.lock()
+        succ = pred.next
+    }
+    ok = true
+    return
+}
+
+func scan[K cmp.Ordered, V any](elem *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the nodes from 'elem' to the tail, until it returns false
+    var last K
+    seen := false
+    for elem.bound != share.BOUND_MAX {
+        if elem.bound == share.BOUND_NONE && (!seen || last < elem.key) { // A deleted node points back to its predecessor: skip what was already visited
+            if !fn(elem.key, *elem.ref.Load()) {
+                return
+            }
+            last = elem.key
+            seen = true
+        }
+        elem = elem.next
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of key not less than 'key' (greater if 'strict'), deleted or not
+    curr := set.head.next
+    for curr.less(key) || (strict && curr.equal(key)) {
+        curr = curr.next
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) *node[K, V] { // 'curr', nil if the tail
+    if curr.bound == share.BOUND_MAX {
+        return nil
+    }
+    return curr
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) *node[K, V] { // Last node among the first nodes satisfying 'in', nil if none
+    var res *node[K, V] = nil
+    curr := set.head.next
+    for curr.bound == share.BOUND_MIN || (curr.bound == share.BOUND_NONE && in(curr)) { // A deleted node may point back to the head
+        if curr.bound == share.BOUND_NONE {
+            res = curr
+        }
+        curr = curr.next
+    }
+    return res
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V]) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, *n.ref.Load(), true
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    left, right := set.search_strong(key)
+    defer left.unlock()
+    var zero V
+    if !right.equal(key) {
+        val, action := fn(zero, false)
+        if action != share.ACTION_STORE {
+            return zero, false
+        }
+        left.next = new_node(key, val, left.next)
+        set.count.Add(1)
+        return val, true
+    }
+    right.lock()
+    defer right.unlock()
+    p := right.ref.Load()
+    val, action := fn(*p, true)
+    switch action {
+    case share.ACTION_STORE:
+        right.ref.Store(&val)
+        return val, true
+    case share.ACTION_DELETE:
+        left.next = right.next
+        right.next = left
+        set.count.Add(-1)
+        return zero, false
+    default:
+        return *p, true
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses AtomicSize
+    set := new(DataSet[K, V])
+    max := new_sentinel[K, V](share.BOUND_MAX, nil)
+    min := new_sentinel(share.BOUND_MIN, max)
+    set.head = min
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    node := set.head.next
+    for node.next != nil {
+        size++
+        node = node.next
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    right := set.search_weak_right(key)
+    if right.equal(key) {
+        return *right.ref.Load(), true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var left, right *node[K, V]
+    if pugh_ro_fail {
+        var ok bool
+        left, right, ok = set.search_strong_cond(key, true)
+        if !ok {
+            return false
+        }
+    } else {
+        left, right = set.search_strong(key)
+    }
+    if right.equal(key) {
+        left.unlock()
+        return false
+    }
+    left.next = new_node(key, val, left.next)
+    left.unlock()
+    set.count.Add(1)
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (result V, ok bool) {
+    var left, right *node[K, V]
+    if pugh_ro_fail {
+        left, right, ok = set.search_strong_cond(key, false)
+        if !ok {
+            return
+        }
+    } else {
+        left, right = set.search_strong(key)
+    }
+    ok = false
+    if right.equal(key) {
+        right.lock()
+        result = *right.ref.Load()
+        left.next = right.next
+        right.next = left
+        right.unlock()
+        ok = true
+        set.count.Add(-1)
+    }
+    left.unlock()
+    return
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Lock every node hand-over-hand from the head, keeping them locked until the tail is reached
+    var res []dataset.Pair[K, V]
+    set.head.lock()
+    curr := set.head.next
+    for curr.bound != share.BOUND_MAX {
+        curr.lock()
+        res = append(res, dataset.Pair[K, V]{Key: curr.key, Val: *curr.ref.Load()})
+        curr = curr.next
+    }
+    for node := set.head; node != curr; node = node.next {
+        node.unlock()
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent, keys being visited in strictly increasing order
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        scan(set.head.next, yield)
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(set.head.next))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
@@ -0,0 +1,262 @@
+/**
+ * @file   priorityqueue_linden.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * J. Lindén and B. Jonsson. A Skiplist-Based Concurrent Priority Queue with
+ * Minimal Memory Contention. In OPODIS 2013, pages 206-220. Springer, 2013.
+ *
+ * The deleted nodes form a prefix of the lowest level, a node being deleted
+ * once the level 0 pointer of its predecessor is marked: 'DeleteMin' marks the
+ * first unmarked pointer from the head, without unlinking anything. Once this
+ * prefix exceeds 'bound_offset' nodes, the deletion that noticed it swings the
+ * head pointers past the whole prefix at once.
+**/
+
+package priorityqueue_linden
+
+import (
+    "cmp"
+    "fmt"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/skiplist"
+)
+
+const (
+    linden_max_level = uint(64)
+    bound_offset = 32 // Length of the deleted prefix above which it is unlinked
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    bound share.Bound
+    toplevel uint32
+    inserting atomic.Bool // Not linked at every level yet, so that the head is not swung past it
+    next []*node[K, V]    // Level 0 pointer marked once the successor is deleted
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    level_max uint
+    head *node[K, V]
+    tail *node[K, V]
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) succ_deleted() bool { // Whether the successor of this node at level 0 is deleted
+    return skiplist.Is_marked(skiplist.Load(&n.next[0]))
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, toplevel uint32, level_max uint) *node[K, V] {
+    elem := new(node[K, V])
+    elem.key = key
+    elem.val = val
+    elem.toplevel = toplevel
+    elem.next = make([]*node[K, V], level_max)
+    return elem
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V], level_max uint) *node[K, V] {
+    var key K
+    var val V
+    elem := new_node(key, val, uint32(level_max), level_max)
+    elem.bound = bound
+    for i := uint(0); i < level_max; i++ {
+        elem.next[i] = next
+    }
+    return elem
+}
+
+// -----------------------------------------------------------------------------
+
+/** Locate the predecessors and successors of a key, the deleted nodes being skipped (at level 0, the whole deleted prefix).
+ * @param key   Key to locate
+ * @param preds Predecessor at each level
+ * @param succs Successor at each level
+ * @return Last node of the deleted prefix traversed at level 0, nil if none
+**/
+func (set *DataSet[K, V]) locate_preds(key K, preds []*node[K, V], succs []*node[K, V]) *node[K, V] {
+    var del *node[K, V]
+    x := set.head
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        x_next := skiplist.Load(&x.next[i])
+        d := skiplist.Is_marked(x_next)
+        x_next = skiplist.Unset_mark(x_next)
+        for x_next.less(key) || x_next.succ_deleted() || (i == 0 && d) {
+            if i == 0 && d {
+                del = x_next
+            }
+            x = x_next
+            x_next = skiplist.Load(&x.next[i])
+            d = skiplist.Is_marked(x_next)
+            x_next = skiplist.Unset_mark(x_next)
+        }
+        preds[i] = x
+        succs[i] = x_next
+    }
+    return del
+}
+
+func (set *DataSet[K, V]) restructure() { // Swing the head pointers of the upper levels past the deleted prefix
+    pred := set.head
+    for i := int(set.level_max - 1); i > 0; {
+        h := skiplist.Load(&set.head.next[i])
+        cur := skiplist.Load(&pred.next[i])
+        if !h.succ_deleted() {
+            i--
+            continue
+        }
+        for cur.succ_deleted() {
+            pred = cur
+            cur = skiplist.Load(&pred.next[i])
+        }
+        if skiplist.Cas(&set.head.next[i], h, cur) {
+            i--
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax
+    level_max := share.Or_default(opts.LevelMax, share.DEFAULT_LEVEL_MAX)
+    if level_max > linden_max_level {
+        return nil, share.Invalid_option("priorityqueue_linden", "maximum level", level_max, fmt.Sprintf("at most %v", linden_max_level))
+    }
+    skiplist.Init_rand_level()
+    set := new(DataSet[K, V])
+    set.level_max = level_max
+    set.tail = new_sentinel[K, V](share.BOUND_MAX, nil, level_max)
+    set.head = new_sentinel(share.BOUND_MIN, set.tail, level_max)
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    size := uint(0)
+    x := set.head
+    for {
+        next := skiplist.Load(&x.next[0])
+        x = skiplist.Unset_mark(next)
+        if x == set.tail {
+            return size
+        }
+        if !skiplist.Is_marked(next) {
+            size++
+        }
+    }
+}
+
+func (set *DataSet[K, V]) InsertWithPriority(key K, val V) bool { // Priorities are unique
+    var preds, succs [linden_max_level]*node[K, V]
+    elem := new_node(key, val, uint32(skiplist.Get_rand_level(set.level_max)), set.level_max)
+    elem.inserting.Store(true)
+    defer elem.inserting.Store(false) // After every CAS
+retry:
+    del := set.locate_preds(key, preds[:], succs[:])
+    if succs[0].equal(key) && skiplist.Load(&preds[0].next[0]) == succs[0] { // Present and not deleted
+        return false
+    }
+    elem.next[0] = succs[0]
+    if !skiplist.Cas(&preds[0].next[0], succs[0], elem) { // Logically inserted once linked at level 0
+        goto retry
+    }
+    for i := uint32(1); i < elem.toplevel; {
+        if elem.succ_deleted() || succs[i].succ_deleted() || del == succs[i] { // Deleted already, or the successor is
+            return true
+        }
+        elem.next[i] = succs[i]
+        if skiplist.Cas(&preds[i].next[i], succs[i], elem) {
+            i++
+            continue
+        }
+        del = set.locate_preds(key, preds[:], succs[:])
+        if succs[0] != elem { // Deleted already
+            return true
+        }
+    }
+    return true
+}
+
+func (set *DataSet[K, V]) DeleteMin() (K, V, bool) {
+    var new_head *node[K, V]
+    offset := 0
+    x := set.head
+    obs_head := skiplist.Load(&x.next[0])
+    for {
+        next := skiplist.Load(&x.next[0])
+        if skiplist.Unset_mark(next) == set.tail {
+            var key K
+            var val V
+            return key, val, false
+        }
+        if new_head == nil && x.inserting.Load() { // Do not swing the head past a node being inserted
+            new_head = x
+        }
+        if skiplist.Is_marked(next) { // Deleted successor, move on
+            offset++
+            x = skiplist.Unset_mark(next)
+            continue
+        }
+        if skiplist.Cas(&x.next[0], next, skiplist.Set_mark(next)) { // Linearization point: the successor gets deleted
+            offset++
+            x = next
+            break
+        }
+    }
+    if new_head == nil {
+        new_head = x
+    }
+    if offset > bound_offset && skiplist.Load(&set.head.next[0]) == obs_head {
+        if skiplist.Cas(&set.head.next[0], obs_head, skiplist.Set_mark(new_head)) {
+            set.restructure()
+        }
+    }
+    return x.key, x.val, true
+}
+
+func (set *DataSet[K, V]) Peek() (K, V, bool) {
+    x := set.head
+    for {
+        next := skiplist.Load(&x.next[0])
+        x = skiplist.Unset_mark(next)
+        if x == set.tail {
+            var key K
+            var val V
+            return key, val, false
+        }
+        if !skiplist.Is_marked(next) { // First node not deleted
+            return x.key, x.val, true
+        }
+    }
+}
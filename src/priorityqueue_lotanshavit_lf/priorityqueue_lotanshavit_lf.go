@@ -0,0 +1,436 @@
+/**
+ * @file   priorityqueue_lotanshavit_lf.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * I. Lotan and N. Shavit. Skiplist-based concurrent priority queues.
+ * In Parallel and Distributed Processing Symposium, 2000. IPDPS 2000. Proceedings.
+ * 14th International, pages 263268. IEEE, 2000.
+ *
+ * D. Alistarh, J. Kopinsky, J. Li and N. Shavit. The SprayList: A Scalable
+ * Relaxed Priority Queue. In PPoPP 2015, pages 11-20. ACM, 2015.
+ *
+ * 'Spray' shares the skip list: its 'DeleteMin' starts from the head at a
+ * level logarithmic in the amount of threads it is tuned for, then walks a
+ * random amount of nodes at each level down to level 0, and deletes the first
+ * live node from there. The deleted element is thus among the first ones, the
+ * concurrent deletions being spread over them instead of all contending on
+ * the minimum.
+**/
+
+package priorityqueue_lotanshavit_lf
+
+import (
+    "cmp"
+    "fmt"
+    "math/bits"
+    "math/rand/v2"
+    "runtime"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/skiplist"
+)
+
+const (
+    fraser_max_level = uint(64)
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    bound share.Bound
+    deleted uint32
+    toplevel uint32
+    next []*node[K, V]
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    level_max uint
+    head *node[K, V]
+}
+
+type Spray[K cmp.Ordered, V any] struct {
+    *DataSet[K, V]
+    threads uint // Amount of threads the spray is tuned for, 1 for an exact 'DeleteMin'
+    height uint  // Level the spray starts from
+    jump uint    // Maximum amount of nodes walked at each level
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) live() bool { // Not deleted, as the deletion is decided by marking level 0
+    return !skiplist.Is_marked(n.next[0])
+}
+
+func new_simple_node[K cmp.Ordered, V any](key K, val V, toplevel uint32, level_max uint) *node[K, V] {
+    elem := new(node[K, V])
+    elem.key = key
+    elem.val = val
+    elem.toplevel = toplevel
+    elem.deleted = 0
+    elem.next = make([]*node[K, V], level_max)
+    return elem
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V], toplevel uint32, level_max uint) *node[K, V] {
+    node := new_simple_node(key, val, toplevel, level_max)
+    for i := uint(0); i < level_max; i++ {
+        node.next[i] = next
+    }
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V], level_max uint) *node[K, V] {
+    var key K
+    var val V
+    elem := new_node(key, val, next, uint32(level_max), level_max)
+    elem.bound = bound
+    return elem
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) fraser_search(key K, left_list []*node[K, V], right_list []*node[K, V]) bool {
+retry:
+    left := set.head
+    var right *node[K, V]
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        left_next := left.next[i]
+        if skiplist.Is_marked(left_next) {
+            goto retry
+        }
+
+        /* Find unmarked node pair at this level */
+        var right_next *node[K, V]
+        for right = left_next;; right = right_next {
+            /* Skip a sequence of marked nodes */
+            right_next = right.next[i]
+            for skiplist.Is_marked(right_next) {
+                right = skiplist.Unset_mark(right_next)
+                right_next = right.next[i]
+            }
+            if !right.less(key) {
+                break
+            }
+            left = right
+            left_next = right_next
+        }
+
+        /* Ensure left and right nodes are adjacent */
+        if left_next != right {
+            if !skiplist.Cas(&left.next[i], left_next, right) {
+                goto retry
+            }
+        }
+
+        if left_list != nil {
+            left_list[i] = left
+        }
+        if right_list != nil {
+            right_list[i] = right
+        }
+    }
+    return right.equal(key)
+}
+
+func (set *DataSet[K, V]) fraser_search_no_cleanup(key K, left_list []*node[K, V], right_list []*node[K, V]) bool {
+    left := set.head
+    var right *node[K, V]
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        left_next := skiplist.Unset_mark(left.next[i])
+        right = left_next
+        for {
+            if !skiplist.Is_marked(right.next[i]) {
+                if !right.less(key) {
+                    break
+                }
+                left = right
+            }
+            right = skiplist.Unset_mark(right.next[i])
+        }
+        left_list[i] = left
+        right_list[i] = right
+    }
+    return right.equal(key)
+}
+
+func (set *DataSet[K, V]) fraser_search_no_cleanup_succs(key K, right_list []*node[K, V]) bool {
+    left := set.head
+    var right *node[K, V]
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        left_next := skiplist.Unset_mark(left.next[i])
+        right = left_next
+        for {
+            if !skiplist.Is_marked(right.next[i]) {
+                if !right.less(key) {
+                    break
+                }
+                left = right
+            }
+            right = skiplist.Unset_mark(right.next[i])
+        }
+        right_list[i] = right
+    }
+    return right.equal(key)
+}
+
+func mark_node_ptrs[K cmp.Ordered, V any](n *node[K, V]) bool {
+    var cas bool = false
+    for i := int(n.toplevel - 1); i >= 0; i-- {
+        for {
+            n_next := n.next[i]
+            if skiplist.Is_marked(n_next) {
+                cas = false
+                break
+            }
+            cas = skiplist.Cas(&n.next[i], skiplist.Unset_mark(n_next), skiplist.Set_mark(n_next))
+            if cas {
+                break
+            }
+        }
+    }
+    return cas
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of level 0 of key not less than 'key' (greater if 'strict'), deleted or not
+    pred := set.head
+    var curr *node[K, V]
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        curr = skiplist.Unset_mark(pred.next[i])
+        for curr.less(key) || (strict && curr.equal(key)) {
+            pred = curr
+            curr = skiplist.Unset_mark(pred.next[i])
+        }
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) *node[K, V] { // First live node of level 0 from 'curr', nil if none
+    for curr.bound != share.BOUND_MAX {
+        if curr.live() {
+            return curr
+        }
+        curr = skiplist.Unset_mark(curr.next[0])
+    }
+    return nil
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) *node[K, V] { // Last live node among the first nodes satisfying 'in', nil if none
+    for {
+        pred := set.head
+        for i := int(set.level_max - 1); i >= 0; i-- {
+            curr := skiplist.Unset_mark(pred.next[i])
+            for curr.bound == share.BOUND_NONE && in(curr) {
+                pred = curr
+                curr = skiplist.Unset_mark(pred.next[i])
+            }
+        }
+        if pred.bound != share.BOUND_NONE {
+            return nil
+        }
+        if pred.live() {
+            return pred
+        }
+        bound := pred.key // Deleted: look for the last node before it
+        in = func(n *node[K, V]) bool { return n.key < bound }
+    }
+}
+
+/** Walk a random amount of nodes at each level, from the head.
+ * @param height Level to start from
+ * @param jump   Maximum amount of nodes walked at each level
+ * @return Node of level 0 the spray landed on (possibly deleted, or the tail), the first one if it stayed on the head
+**/
+func (set *DataSet[K, V]) spray(height uint, jump uint) *node[K, V] {
+    x := set.head
+    for i := int(height - 1); i >= 0; i-- {
+        for steps := rand.IntN(int(jump) + 1); steps > 0; steps-- {
+            next := skiplist.Unset_mark(x.next[i])
+            if next.bound == share.BOUND_MAX {
+                break
+            }
+            x = next
+        }
+    }
+    if x == set.head {
+        return skiplist.Unset_mark(x.next[0])
+    }
+    return x
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V]) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, n.val, true
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax
+    level_max := share.Or_default(opts.LevelMax, share.DEFAULT_LEVEL_MAX)
+    if level_max > fraser_max_level {
+        return nil, share.Invalid_option("priorityqueue_lotanshavit_lf", "maximum level", level_max, fmt.Sprintf("at most %v", fraser_max_level))
+    }
+    skiplist.Init_rand_level()
+    set := new(DataSet[K, V])
+    set.level_max = level_max
+    max := new_sentinel[K, V](share.BOUND_MAX, nil, level_max)
+    min := new_sentinel(share.BOUND_MIN, max, level_max)
+    set.head = min
+    return set, nil
+}
+
+func NewSpray[K cmp.Ordered, V any](opts share.Options) (*Spray[K, V], error) { // Uses LevelMax and Relaxation (amount of threads, by default 'GOMAXPROCS')
+    set, err := New[K, V](opts)
+    if err != nil {
+        return nil, err
+    }
+    threads := share.Or_default(opts.Relaxation, uint(runtime.GOMAXPROCS(0)))
+    log := uint(bits.Len(threads)) // Rounded up logarithm, plus one
+    return &Spray[K, V]{DataSet: set, threads: threads, height: min(log, set.level_max), jump: log}, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    size := uint(0)
+    node := skiplist.Unset_mark(set.head.next[0])
+    for node.next[0] != nil {
+        if !skiplist.Is_marked(node.next[0]) {
+            size++
+        }
+        node = skiplist.Unset_mark(node.next[0])
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) InsertWithPriority(key K, val V) bool { // Priorities are unique
+    var succs, preds [fraser_max_level]*node[K, V]
+retry:
+    found := set.fraser_search_no_cleanup(key, preds[:], succs[:])
+    if found {
+        return false
+    }
+    elem := new_simple_node(key, val, uint32(skiplist.Get_rand_level(set.level_max)), set.level_max)
+    for i := uint32(0); i < elem.toplevel; i++ {
+        elem.next[i] = succs[i]
+    }
+    // Node is visible once inserted at lowest level
+    if !skiplist.Cas(&preds[0].next[0], skiplist.Unset_mark(succs[0]), elem) {
+        goto retry
+    }
+    for i := uint32(1); i < elem.toplevel; i++ {
+        for {
+            pred := preds[i]
+            succ := succs[i]
+            if skiplist.Is_marked(elem.next[i]) {
+                return true
+            }
+            if skiplist.Cas(&pred.next[i], succ, elem) {
+                break
+            }
+            set.fraser_search(key, preds[:], succs[:])
+        }
+    }
+    return true
+}
+
+func (set *DataSet[K, V]) DeleteMin() (K, V, bool) {
+    elem := skiplist.Unset_mark(set.head.next[0])
+    for elem.next[0] != nil {
+        if !skiplist.Is_marked(elem.next[elem.toplevel - 1]) {
+            if mark_node_ptrs(elem) {
+                set.fraser_search(elem.key, nil, nil)
+                return elem.key, elem.val, true
+            }
+        }
+        elem = skiplist.Unset_mark(elem.next[0])
+    }
+    var key K
+    var val V
+    return key, val, false
+}
+
+func (set *Spray[K, V]) DeleteMin() (K, V, bool) { // Among the first O(threads * log(threads)) elements
+    if set.threads > 1 {
+        for elem := first(set.spray(set.height, set.jump)); elem != nil; elem = first(elem) {
+            if mark_node_ptrs(elem) {
+                set.fraser_search(elem.key, nil, nil)
+                return elem.key, elem.val, true
+            }
+        }
+    }
+    return set.DataSet.DeleteMin() // Landed past the last live node, or exact
+}
+
+func (set *Spray[K, V]) Relaxation() uint { // Amount of threads the spray is tuned for
+    return set.threads
+}
+
+func (set *DataSet[K, V]) Peek() (K, V, bool) {
+    elem := skiplist.Unset_mark(set.head.next[0])
+    for elem.next[0] != nil {
+        if !skiplist.Is_marked(elem.next[0]) { // Not deleted yet, as the deletion is decided by marking level 0
+            return elem.key, elem.val, true
+        }
+        elem = skiplist.Unset_mark(elem.next[0])
+    }
+    var key K
+    var val V
+    return key, val, false
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(skiplist.Unset_mark(set.head.next[0])))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
@@ -0,0 +1,236 @@
+/**
+ * @file   priorityqueue_multiqueue.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * H. Rihani, P. Sanders and R. Dementiev. MultiQueues: Simple Relaxed
+ * Concurrent Priority Queues. In SPAA 2015, pages 80-82. ACM, 2015.
+ *
+ * m sequential binary heaps (c x GOMAXPROCS in the paper, c = 2 by default),
+ * each behind a test-and-test-and-set lock, and caching its minimum so that it
+ * can be read without the lock; m is the relaxation, a single heap giving an
+ * exact order.
+ * 'DeleteMin' picks two random heaps, and tries to lock the one of smaller
+ * minimum, picking another two if the lock is taken; the deleted element is
+ * thus among the first O(m) ones, on average.
+ * Priorities being unique, each one is inserted into the heap its hash picks
+ * (rather than a random one), which keeps track of the priorities it holds.
+**/
+
+package priorityqueue_multiqueue
+
+import (
+    "cmp"
+    "fmt"
+    "math/bits"
+    "math/rand/v2"
+    "runtime"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
+)
+
+const (
+    cache_line_size = 64
+    default_factor = 2    // Default amount of heaps per processor
+    max_heaps = 1 << 16   // Maximum amount of heaps
+)
+
+// -----------------------------------------------------------------------------
+
+type item[K cmp.Ordered, V any] struct {
+    key K
+    val V
+}
+
+type heap[K cmp.Ordered, V any] struct {
+    lock ttas.Mutex
+    top atomic.Pointer[item[K, V]] // Minimum of 'items', nil if empty
+    items []*item[K, V]            // Binary heap, only accessed with 'lock' held
+    keys map[K]struct{}            // Priorities in 'items'
+    _ [cache_line_size]byte
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    heaps []heap[K, V]
+    hash share.Hasher[K]
+}
+
+// -----------------------------------------------------------------------------
+
+func (h *heap[K, V]) push(elem *item[K, V]) { // With 'lock' held
+    h.items = append(h.items, elem)
+    i := len(h.items) - 1
+    for i > 0 {
+        parent := (i - 1) / 2
+        if h.items[parent].key <= elem.key {
+            break
+        }
+        h.items[i] = h.items[parent]
+        i = parent
+    }
+    h.items[i] = elem
+    h.keys[elem.key] = struct{}{}
+    h.top.Store(h.items[0])
+}
+
+func (h *heap[K, V]) pop() *item[K, V] { // With 'lock' held, and 'items' not empty
+    res := h.items[0]
+    n := len(h.items) - 1
+    last := h.items[n]
+    h.items[n] = nil // Release the element
+    h.items = h.items[:n]
+    if n > 0 {
+        i := 0
+        for {
+            child := 2 * i + 1
+            if child >= n {
+                break
+            }
+            if child + 1 < n && h.items[child + 1].key < h.items[child].key {
+                child++
+            }
+            if last.key <= h.items[child].key {
+                break
+            }
+            h.items[i] = h.items[child]
+            i = child
+        }
+        h.items[i] = last
+        h.top.Store(h.items[0])
+    } else {
+        h.top.Store(nil)
+    }
+    delete(h.keys, res.key)
+    return res
+}
+
+func less[K cmp.Ordered, V any](a *item[K, V], b *item[K, V]) bool { // Whether the minimum 'a' precedes 'b', nil for an empty heap
+    return a != nil && (b == nil || a.key < b.key)
+}
+
+func (set *DataSet[K, V]) scan() (*item[K, V], bool) { // Delete from the first non-empty heap, from a random one
+    start := rand.IntN(len(set.heaps))
+    for i := range set.heaps {
+        h := &set.heaps[(start + i) % len(set.heaps)]
+        if h.top.Load() == nil {
+            continue
+        }
+        h.lock.Lock()
+        if len(h.items) > 0 {
+            res := h.pop()
+            h.lock.Unlock()
+            return res, true
+        }
+        h.lock.Unlock()
+    }
+    return nil, false
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses Relaxation (amount of heaps, 2 x 'GOMAXPROCS' by default)
+    num_heaps := share.Or_default(opts.Relaxation, default_factor * uint(runtime.GOMAXPROCS(0)))
+    if num_heaps > max_heaps {
+        return nil, share.Invalid_option("priorityqueue_multiqueue", "relaxation", num_heaps, fmt.Sprintf("at most %v heaps", max_heaps))
+    }
+    set := new(DataSet[K, V])
+    set.heaps = make([]heap[K, V], num_heaps)
+    for i := range set.heaps {
+        set.heaps[i].keys = make(map[K]struct{})
+    }
+    set.hash = share.NewHasher[K]()
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    size := uint(0)
+    for i := range set.heaps {
+        h := &set.heaps[i]
+        h.lock.Lock()
+        size += uint(len(h.items))
+        h.lock.Unlock()
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) InsertWithPriority(key K, val V) bool { // Priorities are unique
+    hi, _ := bits.Mul64(uint64(set.hash(key)) * 0x9e3779b97f4a7c15, uint64(len(set.heaps))) // Fibonacci hashing, so that close priorities spread
+    h := &set.heaps[hi]
+    h.lock.Lock()
+    defer h.lock.Unlock()
+    if _, ok := h.keys[key]; ok {
+        return false
+    }
+    h.push(&item[K, V]{key, val})
+    return true
+}
+
+func (set *DataSet[K, V]) DeleteMin() (K, V, bool) {
+    for {
+        a := &set.heaps[rand.IntN(len(set.heaps))]
+        b := &set.heaps[rand.IntN(len(set.heaps))]
+        a_top, b_top := a.top.Load(), b.top.Load()
+        if a_top == nil && b_top == nil { // Both empty, look for any non-empty heap
+            res, ok := set.scan()
+            if !ok {
+                var key K
+                var val V
+                return key, val, false
+            }
+            return res.key, res.val, true
+        }
+        if less(b_top, a_top) {
+            a = b
+        }
+        if !a.lock.TryLock() {
+            runtime.Gosched() // Its holder may be descheduled, let it run
+            continue
+        }
+        if len(a.items) == 0 { // Emptied meanwhile
+            a.lock.Unlock()
+            continue
+        }
+        res := a.pop()
+        a.lock.Unlock()
+        return res.key, res.val, true
+    }
+}
+
+func (set *DataSet[K, V]) Peek() (K, V, bool) { // Smallest cached minimum
+    var res *item[K, V]
+    for i := range set.heaps {
+        if top := set.heaps[i].top.Load(); less(top, res) {
+            res = top
+        }
+    }
+    if res == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return res.key, res.val, true
+}
+
+func (set *DataSet[K, V]) Relaxation() uint { // Amount of heaps
+    return uint(len(set.heaps))
+}
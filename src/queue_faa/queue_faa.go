@@ -0,0 +1,169 @@
+/**
+ * @file   queue_faa.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Lock-free fetch-and-add queue, a linked list of array segments as in LCRQ.
+ * Enqueuers and dequeuers each reserve a slot of the last (resp. first)
+ * segment with a fetch-and-add on its index, so that they only contend on a
+ * CAS when a segment is full (resp. drained).
+ * An enqueuer then stores its value with a CAS on the empty slot; a dequeuer
+ * swaps the slot with a "taken" sentinel, which makes the enqueuer that
+ * reserved it but was too slow retry on another slot.
+ * So that the dequeuers cannot starve an enqueuer this way, an enqueuer that
+ * lost 'max_attempts' slots appends a new segment holding its value instead,
+ * as if the last segment were full: one of the competing appends succeeds.
+**/
+
+package queue_faa
+
+import (
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    cache_line_size = 64
+    segment_size = 1024 // Amount of slots per segment
+    max_attempts = 16   // Amount of slots an enqueuer may lose to the dequeuers, before it appends a new segment
+)
+
+// -----------------------------------------------------------------------------
+
+type segment[V any] struct {
+    deq atomic.Uint64 // Next slot to dequeue (may exceed 'segment_size')
+    _ [cache_line_size - 8]byte
+    enq atomic.Uint64 // Next slot to enqueue (may exceed 'segment_size')
+    _ [cache_line_size - 8]byte
+    next atomic.Pointer[segment[V]]
+    slots [segment_size]atomic.Pointer[V] // Boxed values, nil if not enqueued yet, or 'taken'
+}
+
+type DataSet[V any] struct {
+    head atomic.Pointer[segment[V]]
+    _ [cache_line_size - 8]byte
+    tail atomic.Pointer[segment[V]]
+    _ [cache_line_size - 8]byte
+    taken *V // Sentinel of the dequeued slots, distinct from any boxed value
+}
+
+// -----------------------------------------------------------------------------
+
+func new_segment[V any](val *V) *segment[V] { // New segment, holding 'val' in its first slot (if not nil)
+    seg := new(segment[V])
+    if val != nil {
+        seg.slots[0].Store(val)
+        seg.enq.Store(1)
+    }
+    return seg
+}
+
+// -----------------------------------------------------------------------------
+
+func New[V any](opts share.Options) (*DataSet[V], error) { // No option used
+    set := new(DataSet[V])
+    seg := new_segment[V](nil)
+    set.head.Store(seg)
+    set.tail.Store(seg)
+    set.taken = share.New_sentinel[V]()
+    return set, nil
+}
+
+func (set *DataSet[V]) Destroy() {
+}
+
+func (set *DataSet[V]) Size() uint {
+    size := uint(0)
+    for seg := set.head.Load(); seg != nil; seg = seg.next.Load() {
+        for i := range seg.slots {
+            val := seg.slots[i].Load()
+            if val != nil && val != set.taken {
+                size++
+            }
+        }
+    }
+    return size
+}
+
+func (set *DataSet[V]) Enqueue(val V) {
+    elem := &val
+    attempts := 0
+    for {
+        tail := set.tail.Load()
+        if attempts < max_attempts {
+            idx := tail.enq.Add(1) - 1
+            if idx < segment_size {
+                if tail.slots[idx].CompareAndSwap(nil, elem) {
+                    return
+                }
+                attempts++
+                continue // Taken by a dequeuer
+            }
+        }
+        if tail != set.tail.Load() { // Full segment (or too many attempts), append a new one (unless already done)
+            continue
+        }
+        next := tail.next.Load()
+        if next == nil {
+            seg := new_segment(elem)
+            if tail.next.CompareAndSwap(nil, seg) {
+                set.tail.CompareAndSwap(tail, seg)
+                return
+            }
+        } else {
+            set.tail.CompareAndSwap(tail, next)
+        }
+    }
+}
+
+func (set *DataSet[V]) Dequeue() (V, bool) {
+    for {
+        head := set.head.Load()
+        if head.deq.Load() >= head.enq.Load() && head.next.Load() == nil { // Empty
+            var zero V
+            return zero, false
+        }
+        idx := head.deq.Add(1) - 1
+        if idx < segment_size {
+            if val := head.slots[idx].Swap(set.taken); val != nil {
+                return *val, true
+            }
+            continue // Reserved but not enqueued yet, the enqueuer will retry
+        }
+        next := head.next.Load() // Drained segment, move to the next one
+        if next == nil {
+            var zero V
+            return zero, false
+        }
+        set.head.CompareAndSwap(head, next)
+    }
+}
+
+func (set *DataSet[V]) Peek() (V, bool) { // Oldest element not being dequeued
+    for seg := set.head.Load(); seg != nil; seg = seg.next.Load() { // Even past a segment not full, which a starved enqueuer may have followed with a new one
+        enq := min(seg.enq.Load(), segment_size)
+        for idx := seg.deq.Load(); idx < enq; idx++ {
+            if val := seg.slots[idx].Load(); val != nil && val != set.taken {
+                return *val, true
+            }
+        }
+    }
+    var zero V
+    return zero, false
+}
@@ -23,37 +23,31 @@
  * A simple lock-based queue.
 **/
 
-package dataset
+package queue_ms_lb
 
 import (
-    "tools/share"
-    "tools/ttas"
-)
-
-const (
-    FindIsDef bool = false
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
 )
 
 // -----------------------------------------------------------------------------
 
-type node struct {
-    key share.Key
-    val share.Val
-    next *node
+type node[V any] struct {
+    val V
+    next *node[V]
 }
 
-type DataSet struct {
-    head *node
-    tail *node
+type DataSet[V any] struct {
+    head *node[V]
+    tail *node[V]
     head_lock ttas.Mutex
     tail_lock ttas.Mutex
 }
 
 // -----------------------------------------------------------------------------
 
-func new_node(key share.Key, val share.Val, next *node) *node {
-    elem := new(node)
-    elem.key = key
+func new_node[V any](val V, next *node[V]) *node[V] {
+    elem := new(node[V])
     elem.val = val
     elem.next = next
     return elem
@@ -61,18 +55,18 @@ func new_node(key share.Key, val share.Val, next *node) *node {
 
 // -----------------------------------------------------------------------------
 
-func New() *DataSet {
-    set := new(DataSet)
-    node := new_node(0, 0, nil)
+func New[V any](opts share.Options) (*DataSet[V], error) { // No option used
+    set := new(DataSet[V])
+    node := new(node[V]) // Dummy node
     set.head = node
     set.tail = node
-    return set
+    return set, nil
 }
 
-func (set *DataSet) Destroy() {
+func (set *DataSet[V]) Destroy() {
 }
 
-func (set *DataSet) Size() uint {
+func (set *DataSet[V]) Size() uint {
     size := uint(0)
     node := set.head
     for node.next != nil {
@@ -82,27 +76,34 @@ func (set *DataSet) Size() uint {
     return size
 }
 
-func (set *DataSet) Find(key share.Key) (share.Val, bool) {
-    return 0, true
-}
-
-func (set *DataSet) Insert(key share.Key, val share.Val) bool {
-    node := new_node(key, val, nil)
+func (set *DataSet[V]) Enqueue(val V) {
+    node := new_node(val, nil)
     set.tail_lock.Lock()
     defer set.tail_lock.Unlock()
     set.tail.next = node
     set.tail = node
-    return true
 }
 
-func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
+func (set *DataSet[V]) Dequeue() (V, bool) {
     set.head_lock.Lock()
     defer set.head_lock.Unlock()
     node := set.head
     head_new := node.next
     if head_new == nil {
-        return 0, false
+        var zero V
+        return zero, false
     }
     set.head = head_new
     return head_new.val, true
 }
+
+func (set *DataSet[V]) Peek() (V, bool) {
+    set.head_lock.Lock()
+    defer set.head_lock.Unlock()
+    head_new := set.head.next
+    if head_new == nil {
+        var zero V
+        return zero, false
+    }
+    return head_new.val, true
+}
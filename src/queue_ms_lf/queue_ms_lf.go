@@ -23,37 +23,32 @@
  * A simple lock-free queue.
 **/
 
-package dataset
+package queue_ms_lf
 
 import (
     "runtime"
     "sync/atomic"
-    "tools/share"
     "unsafe"
-)
 
-const (
-    FindIsDef bool = false
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
 )
 
 // -----------------------------------------------------------------------------
 
-type node struct {
-    key share.Key
-    val share.Val
-    next *node
+type node[V any] struct {
+    val V
+    next *node[V]
 }
 
-type DataSet struct {
-    head *node
-    tail *node
+type DataSet[V any] struct {
+    head *node[V]
+    tail *node[V]
 }
 
 // -----------------------------------------------------------------------------
 
-func new_node(key share.Key, val share.Val, next *node) *node {
-    elem := new(node)
-    elem.key = key
+func new_node[V any](val V, next *node[V]) *node[V] {
+    elem := new(node[V])
     elem.val = val
     elem.next = next
     return elem
@@ -61,18 +56,18 @@ func new_node(key share.Key, val share.Val, next *node) *node {
 
 // -----------------------------------------------------------------------------
 
-func New() *DataSet {
-    set := new(DataSet)
-    node := new_node(0, 0, nil)
+func New[V any](opts share.Options) (*DataSet[V], error) { // No option used
+    set := new(DataSet[V])
+    node := new(node[V]) // Dummy node
     set.head = node
     set.tail = node
-    return set
+    return set, nil
 }
 
-func (set *DataSet) Destroy() {
+func (set *DataSet[V]) Destroy() {
 }
 
-func (set *DataSet) Size() uint {
+func (set *DataSet[V]) Size() uint {
     size := uint(0)
     node := set.head
     for node.next != nil {
@@ -82,13 +77,9 @@ func (set *DataSet) Size() uint {
     return size
 }
 
-func (set *DataSet) Find(key share.Key) (share.Val, bool) {
-    return 0, true
-}
-
-func (set *DataSet) Insert(key share.Key, val share.Val) bool {
-    elem := new_node(key, val, nil)
-    var tail *node
+func (set *DataSet[V]) Enqueue(val V) {
+    elem := new_node(val, nil)
+    var tail *node[V]
     for {
         tail = set.tail
         next := tail.next
@@ -104,11 +95,10 @@ func (set *DataSet) Insert(key share.Key, val share.Val) bool {
         runtime.Gosched()
     }
     atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&set.tail)), unsafe.Pointer(tail), unsafe.Pointer(elem))
-    return true
 }
 
-func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
-    var next *node
+func (set *DataSet[V]) Dequeue() (V, bool) {
+    var next *node[V]
     for {
         head := set.head
         tail := set.tail
@@ -116,7 +106,8 @@ func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
         if head == set.head {
             if head == tail {
                 if next == nil {
-                    return 0, false
+                    var zero V
+                    return zero, false
                 }
                 atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&set.tail)), unsafe.Pointer(tail), unsafe.Pointer(next))
             } else {
@@ -129,3 +120,18 @@ func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
     }
     return next.val, true
 }
+
+func (set *DataSet[V]) Peek() (V, bool) {
+    for {
+        head := set.head
+        next := head.next
+        if head == set.head { // 'next' was the oldest element when read
+            if next == nil {
+                var zero V
+                return zero, false
+            }
+            return next.val, true
+        }
+        runtime.Gosched()
+    }
+}
@@ -24,40 +24,35 @@
  * Optik lock.
 **/
 
-package dataset
+package queue_optik2
 
 import (
     "runtime"
     "sync/atomic"
-    "tools/optik"
-    "tools/share"
     "unsafe"
-)
 
-const (
-    FindIsDef bool = false
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/optik"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
 )
 
 // -----------------------------------------------------------------------------
 
-type node struct {
-    key share.Key
-    val share.Val
-    next *node
+type node[V any] struct {
+    val V
+    next *node[V]
 }
 
-type DataSet struct {
-    head *node
-    tail *node
+type DataSet[V any] struct {
+    head *node[V]
+    tail *node[V]
     head_lock optik.Mutex
     tail_lock optik.Mutex
 }
 
 // -----------------------------------------------------------------------------
 
-func new_node(key share.Key, val share.Val, next *node) *node {
-    elem := new(node)
-    elem.key = key
+func new_node[V any](val V, next *node[V]) *node[V] {
+    elem := new(node[V])
     elem.val = val
     elem.next = next
     return elem
@@ -65,18 +60,18 @@ func new_node(key share.Key, val share.Val, next *node) *node {
 
 // -----------------------------------------------------------------------------
 
-func New() *DataSet {
-    set := new(DataSet)
-    node := new_node(0, 0, nil)
+func New[V any](opts share.Options) (*DataSet[V], error) { // No option used
+    set := new(DataSet[V])
+    node := new(node[V]) // Dummy node
     set.head = node
     set.tail = node
-    return set
+    return set, nil
 }
 
-func (set *DataSet) Destroy() {
+func (set *DataSet[V]) Destroy() {
 }
 
-func (set *DataSet) Size() uint {
+func (set *DataSet[V]) Size() uint {
     size := uint(0)
     node := set.head
     for node.next != nil {
@@ -86,13 +81,9 @@ func (set *DataSet) Size() uint {
     return size
 }
 
-func (set *DataSet) Find(key share.Key) (share.Val, bool) {
-    return 0, true
-}
-
-func (set *DataSet) Insert(key share.Key, val share.Val) bool {
-    elem := new_node(key, val, nil)
-    var tail *node
+func (set *DataSet[V]) Enqueue(val V) {
+    elem := new_node(val, nil)
+    var tail *node[V]
     for {
         tail = set.tail
         next := tail.next
@@ -108,16 +99,16 @@ func (set *DataSet) Insert(key share.Key, val share.Val) bool {
         runtime.Gosched()
     }
     atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&set.tail)), unsafe.Pointer(tail), unsafe.Pointer(elem))
-    return true
 }
 
-func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
+func (set *DataSet[V]) Dequeue() (V, bool) {
     for {
         version := set.head_lock.Load() // No reorder here
         node := set.head
         head_new := node.next
         if head_new == nil {
-            return 0, false
+            var zero V
+            return zero, false
         }
         if !set.head_lock.TryLock_version(version) {
             runtime.Gosched()
@@ -128,3 +119,18 @@ func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
         return head_new.val, true
     }
 }
+
+func (set *DataSet[V]) Peek() (V, bool) {
+    for {
+        version := set.head_lock.Get_version_wait()
+        head_new := set.head.next
+        if optik.Is_same_version(version, set.head_lock.Load()) { // The head did not move meanwhile
+            if head_new == nil {
+                var zero V
+                return zero, false
+            }
+            return head_new.val, true
+        }
+        runtime.Gosched()
+    }
+}
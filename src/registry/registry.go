@@ -0,0 +1,334 @@
+/**
+ * @file   registry.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Registry of every data structure, by name, so that a single binary can
+ * list and instantiate all of them at runtime.
+**/
+
+package registry
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/bst_ellen"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/bst_howley"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/bst_natarajan"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/bst_optik"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/bst_tk"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/deque_chaselev"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_clht_lb"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_clht_lf"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_copy"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_go_postpone"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_go_sequential"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_go_server"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_go_syncmap"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_java"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_lists"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_lock"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_optik1"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/hashtable_split"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_coupling"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_harris"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_harris_opt"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_lazy"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_michael"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_optik"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_optik_cache"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/linkedlist_pugh"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/priorityqueue_linden"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/priorityqueue_lotanshavit_lf"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/priorityqueue_multiqueue"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/queue_faa"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/queue_ms_lb"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/queue_ms_lf"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/queue_optik1"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/queue_optik2"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_fraser"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_herlihy_lb"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_herlihy_lf"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_lock"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_nohotspot"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_optik1"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_optik2"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_pugh"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_seq"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/stack_elimination"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/stack_flatcombining"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/stack_lock"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/stack_treiber"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+// Data structures, as instantiated by the registry
+type Container = dataset.Container
+type Set = dataset.Set[share.Key, share.Val]
+type SnapshotSet = dataset.SnapshotSet[share.Key, share.Val]
+type BulkSet = dataset.BulkSet[share.Key, share.Val]
+type BatchSet = dataset.BatchSet[share.Key, share.Val]
+type Map = dataset.Map[share.Key, share.Val]
+type OrderedSet = dataset.OrderedSet[share.Key, share.Val]
+type AtomicOrderedSet = dataset.AtomicOrderedSet[share.Key, share.Val]
+type Queue = dataset.Queue[share.Val]
+type Stack = dataset.Stack[share.Val]
+type PriorityQueue = dataset.PriorityQueue[share.Key, share.Val]
+type RelaxedPriorityQueue = dataset.RelaxedPriorityQueue[share.Key, share.Val]
+type Deque = dataset.Deque[share.Val]
+
+// Registered data structure
+type Entry struct {
+    Name       string                                      // Name of the algorithm, e.g. "linkedlist_optik"
+    Kind       dataset.Kind                                // Kind of data structure, i.e. interface implemented by the instances
+    New        func(opts share.Options) (Container, error) // Instantiate a new data structure, with the given options
+    Sequential bool                                        // Not thread-safe, only testable with a single thread
+}
+
+// Operations of any kind of data structure, as used by the generic test modules
+type Ops struct {
+    Put         func(key share.Key, val share.Val) bool                                        // Insert, Enqueue, Push or InsertWithPriority
+    Remove      func(key share.Key) (share.Val, bool)                                          // Delete, Dequeue, Pop or DeleteMin (key ignored but for Delete)
+    Get         func(key share.Key) (share.Val, bool)                                          // Find or Peek (key ignored but for Find)
+    Range       func(lo share.Key, hi share.Key, fn func(key share.Key, val share.Val) bool) // Range, nil if not an ordered set
+    RangeAtomic func(lo share.Key, hi share.Key, fn func(key share.Key, val share.Val) bool) // RangeAtomic, nil if not available
+}
+
+// -----------------------------------------------------------------------------
+
+func wrap[T Container](new func(share.Options) (T, error)) func(share.Options) (Container, error) {
+    return func(opts share.Options) (Container, error) {
+        res, err := new(opts)
+        if err != nil {
+            return nil, err // Not a typed nil
+        }
+        return res, nil
+    }
+}
+
+func set[T Set](name string, new func(share.Options) (T, error)) Entry {
+    return Entry{name, dataset.KIND_SET, wrap(new), false}
+}
+
+func queue[T Queue](name string, new func(share.Options) (T, error)) Entry {
+    return Entry{name, dataset.KIND_QUEUE, wrap(new), false}
+}
+
+func stack[T Stack](name string, new func(share.Options) (T, error)) Entry {
+    return Entry{name, dataset.KIND_STACK, wrap(new), false}
+}
+
+func priority_queue[T PriorityQueue](name string, new func(share.Options) (T, error)) Entry {
+    return Entry{name, dataset.KIND_PRIORITY_QUEUE, wrap(new), false}
+}
+
+func deque[T Deque](name string, new func(share.Options) (T, error)) Entry {
+    return Entry{name, dataset.KIND_DEQUE, wrap(new), false}
+}
+
+func sequential(entry Entry) Entry {
+    entry.Sequential = true
+    return entry
+}
+
+// Every registered data structure, sorted by name
+var entries = []Entry{
+    set("bst_ellen", bst_ellen.New[share.Key, share.Val]),
+    set("bst_howley", bst_howley.New[share.Key, share.Val]),
+    set("bst_natarajan", bst_natarajan.New[share.Key, share.Val]),
+    set("bst_optik", bst_optik.New[share.Key, share.Val]),
+    set("bst_tk", bst_tk.New[share.Key, share.Val]),
+    deque("deque_chaselev", deque_chaselev.New[share.Val]),
+    set("hashtable_clht_lb", hashtable_clht_lb.New[share.Key, share.Val]),
+    set("hashtable_clht_lf", hashtable_clht_lf.New[share.Key, share.Val]),
+    set("hashtable_copy", hashtable_copy.New[share.Key, share.Val]),
+    set("hashtable_go_postpone", hashtable_go_postpone.New[share.Key, share.Val]),
+    sequential(set("hashtable_go_sequential", hashtable_go_sequential.New[share.Key, share.Val])),
+    set("hashtable_go_server", hashtable_go_server.New[share.Key, share.Val]),
+    set("hashtable_go_syncmap", hashtable_go_syncmap.New[share.Key, share.Val]),
+    set("hashtable_harris_opt", hashtable_lists.NewHarrisOpt[share.Key, share.Val]),
+    set("hashtable_java", hashtable_java.New[share.Key, share.Val]),
+    set("hashtable_lazy", hashtable_lists.NewLazy[share.Key, share.Val]),
+    set("hashtable_lock", hashtable_lock.New[share.Key, share.Val]),
+    set("hashtable_optik", hashtable_lists.NewOptik[share.Key, share.Val]),
+    set("hashtable_optik1", hashtable_optik1.New[share.Key, share.Val]),
+    set("hashtable_pugh", hashtable_lists.NewPugh[share.Key, share.Val]),
+    set("hashtable_rwlock", hashtable_lock.NewRW[share.Key, share.Val]),
+    set("hashtable_split", hashtable_split.New[share.Key, share.Val]),
+    set("linkedlist_coupling", linkedlist_coupling.New[share.Key, share.Val]),
+    set("linkedlist_harris", linkedlist_harris.New[share.Key, share.Val]),
+    set("linkedlist_harris_opt", linkedlist_harris_opt.New[share.Key, share.Val]),
+    set("linkedlist_lazy", linkedlist_lazy.New[share.Key, share.Val]),
+    set("linkedlist_michael", linkedlist_michael.New[share.Key, share.Val]),
+    set("linkedlist_optik", linkedlist_optik.New[share.Key, share.Val]),
+    set("linkedlist_optik_cache", linkedlist_optik_cache.New[share.Key, share.Val]),
+    set("linkedlist_pugh", linkedlist_pugh.New[share.Key, share.Val]),
+    priority_queue("priorityqueue_linden", priorityqueue_linden.New[share.Key, share.Val]),
+    priority_queue("priorityqueue_lotanshavit_lf", priorityqueue_lotanshavit_lf.New[share.Key, share.Val]),
+    priority_queue("priorityqueue_multiqueue", priorityqueue_multiqueue.New[share.Key, share.Val]),
+    priority_queue("priorityqueue_spray", priorityqueue_lotanshavit_lf.NewSpray[share.Key, share.Val]),
+    queue("queue_faa", queue_faa.New[share.Val]),
+    queue("queue_ms_lb", queue_ms_lb.New[share.Val]),
+    queue("queue_ms_lf", queue_ms_lf.New[share.Val]),
+    queue("queue_optik1", queue_optik1.New[share.Val]),
+    queue("queue_optik2", queue_optik2.New[share.Val]),
+    set("skiplist_fraser", skiplist_fraser.New[share.Key, share.Val]),
+    set("skiplist_herlihy_lb", skiplist_herlihy_lb.New[share.Key, share.Val]),
+    set("skiplist_herlihy_lf", skiplist_herlihy_lf.New[share.Key, share.Val]),
+    set("skiplist_lock", skiplist_lock.New[share.Key, share.Val]),
+    set("skiplist_nohotspot", skiplist_nohotspot.New[share.Key, share.Val]),
+    set("skiplist_optik1", skiplist_optik1.New[share.Key, share.Val]),
+    set("skiplist_optik2", skiplist_optik2.New[share.Key, share.Val]),
+    set("skiplist_pugh", skiplist_pugh.New[share.Key, share.Val]),
+    set("skiplist_rwlock", skiplist_lock.NewRW[share.Key, share.Val]),
+    sequential(set("skiplist_seq", skiplist_seq.New[share.Key, share.Val])),
+    stack("stack_elimination", stack_elimination.New[share.Val]),
+    stack("stack_flatcombining", stack_flatcombining.New[share.Val]),
+    stack("stack_lock", stack_lock.New[share.Val]),
+    stack("stack_treiber", stack_treiber.New[share.Val]),
+}
+
+// -----------------------------------------------------------------------------
+
+/** Map the operations of the test modules on the API of a data structure of this entry.
+ * @param ds Data structure, instantiated with 'entry.New'
+ * @return Operations on 'ds', panics for a deque (whose owner operations cannot be shared)
+**/
+func (entry Entry) Ops(ds Container) Ops {
+    switch entry.Kind {
+    case dataset.KIND_SET:
+        set := ds.(Set)
+        ops := Ops{Put: set.Insert, Remove: set.Delete, Get: set.Find}
+        if ordered, ok := ds.(OrderedSet); ok {
+            ops.Range = ordered.Range
+        }
+        if atomic, ok := ds.(AtomicOrderedSet); ok {
+            ops.RangeAtomic = atomic.RangeAtomic
+        }
+        return ops
+    case dataset.KIND_QUEUE:
+        queue := ds.(Queue)
+        return Ops{
+            Put: func(key share.Key, val share.Val) bool { queue.Enqueue(val); return true },
+            Remove: func(key share.Key) (share.Val, bool) { return queue.Dequeue() },
+            Get: func(key share.Key) (share.Val, bool) { return queue.Peek() },
+        }
+    case dataset.KIND_STACK:
+        stack := ds.(Stack)
+        return Ops{
+            Put: func(key share.Key, val share.Val) bool { stack.Push(val); return true },
+            Remove: func(key share.Key) (share.Val, bool) { return stack.Pop() },
+            Get: func(key share.Key) (share.Val, bool) { return stack.Peek() },
+        }
+    case dataset.KIND_PRIORITY_QUEUE:
+        pq := ds.(PriorityQueue)
+        return Ops{
+            Put: pq.InsertWithPriority,
+            Remove: func(key share.Key) (share.Val, bool) { _, val, ok := pq.DeleteMin(); return val, ok },
+            Get: func(key share.Key) (share.Val, bool) { _, val, ok := pq.Peek(); return val, ok },
+        }
+    case dataset.KIND_DEQUE:
+        panic("a deque has a single owner, use the deque test module")
+    default:
+        panic("unknown kind of data structure")
+    }
+}
+
+/** Check whether the generic test modules, sharing every operation between their threads, can run this entry.
+ * @return False for a deque, or a sequential data structure
+**/
+func (entry Entry) Shareable() bool {
+    return entry.Kind != dataset.KIND_DEQUE && !entry.Sequential
+}
+
+/** Check whether the generic test modules can run this entry with the given amount of threads.
+ * @param num_threads Amount of test threads
+ * @return Error if it is a deque, or a sequential data structure and 'num_threads' is greater than 1
+**/
+func (entry Entry) Testable(num_threads uint) error {
+    if entry.Kind == dataset.KIND_DEQUE {
+        return fmt.Errorf("'%s' cannot be shared between the test threads, use the deque test module", entry.Name)
+    }
+    if entry.Sequential && num_threads > 1 {
+        return fmt.Errorf("'%s' is not thread-safe, test it with a single thread", entry.Name)
+    }
+    return nil
+}
+
+/** Get every registered data structure.
+ * @return Registered data structures, sorted by name
+**/
+func Entries() []Entry {
+    res := make([]Entry, len(entries))
+    copy(res, entries)
+    return res
+}
+
+/** Get the names of every registered data structure.
+ * @return Names, sorted
+**/
+func Names() []string {
+    res := make([]string, len(entries))
+    for i, entry := range entries {
+        res[i] = entry.Name
+    }
+    return res
+}
+
+/** Look for a registered data structure.
+ * @param name Name of the data structure
+ * @return Registered data structure, and whether it was found
+**/
+func Lookup(name string) (Entry, bool) {
+    for _, entry := range entries {
+        if entry.Name == name {
+            return entry, true
+        }
+    }
+    return Entry{}, false
+}
+
+/** Select registered data structures from a comma-separated list of names.
+ * @param list Comma-separated list of names, or "all" for every data structure
+ * @return Selected data structures, in the order of the list
+**/
+func Select(list string) ([]Entry, error) {
+    if list == "all" {
+        return Entries(), nil
+    }
+    var res []Entry
+    for _, name := range strings.Split(list, ",") {
+        name = strings.TrimSpace(name)
+        if name == "" {
+            continue
+        }
+        entry, ok := Lookup(name)
+        if !ok {
+            return nil, fmt.Errorf("unknown data structure '%s'", name)
+        }
+        res = append(res, entry)
+    }
+    if len(res) == 0 {
+        return nil, fmt.Errorf("no data structure selected")
+    }
+    return res, nil
+}
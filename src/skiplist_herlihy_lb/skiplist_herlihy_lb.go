@@ -0,0 +1,555 @@
+/**
+ * @file   skiplist_herlihy_lb.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Fine-grained locking skip list.
+ * C implementation of the Herlihy et al. algorithm originally designed for managed programming language.
+ * "A Simple Optimistic Skiplist Algorithm", M. Herlihy, Y. Lev, V. Luchangco, N. Shavit, p.124-138, SIROCCO 2007.
+**/
+
+package skiplist_herlihy_lb
+
+import (
+    "cmp"
+    "fmt"
+    "iter"
+    "runtime"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+const (
+    herlihy_max_level = uint(64)
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced (under the lock)
+    bound share.Bound
+    toplevel uint32
+    marked bool
+    fullylinked bool
+    lock ttas.Mutex
+    next []*node[K, V]
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    level_max uint
+    head *node[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+var state xorshift.State
+
+func init_rand_level() {
+    state.Init()
+}
+
+func get_rand_level(level_max uint) uint {
+    level := uint(1)
+    for i := uint(0); i < level_max - 1; i++ {
+        if state.Intn(100) < 50 {
+            level++
+        } else {
+            break
+        }
+    }
+    return level
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) value() *V { // Current value, nil if not inserted at every level or logically deleted
+    if !n.fullylinked || n.marked {
+        return nil
+    }
+    return n.ref.Load()
+}
+
+func new_simple_node[K cmp.Ordered, V any](key K, val V, toplevel uint32) *node[K, V] {
+    elem := new(node[K, V])
+    elem.key = key
+    elem.val = val
+    elem.ref.Store(&elem.val)
+    elem.toplevel = toplevel
+    elem.marked = false
+    elem.fullylinked = false
+    elem.next = make([]*node[K, V], toplevel)
+    return elem
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V], toplevel uint32) *node[K, V] {
+    node := new_simple_node(key, val, toplevel)
+    for i := uint32(0); i < toplevel; i++ {
+        node.next[i] = next
+    }
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V], level_max uint) *node[K, V] {
+    var key K
+    var val V
+    elem := new_node(key, val, next, uint32(level_max))
+    elem.bound = bound
+    return elem
+}
+
+// -----------------------------------------------------------------------------
+
+func ok_to_delete[K cmp.Ordered, V any](elem *node[K, V], found int) bool {
+    return elem.fullylinked && (int(elem.toplevel - 1) == found) && !elem.marked
+}
+
+func (set *DataSet[K, V]) optimistic_search(key K, preds []*node[K, V], succs []*node[K, V]) int {
+restart:
+    found := -1
+    pred := set.head
+    for i := int(pred.toplevel - 1); i >= 0; i-- {
+        curr := pred.next[i]
+        for curr.less(key) {
+            pred = curr
+            curr = pred.next[i]
+        }
+        if preds != nil {
+            preds[i] = pred
+            if pred.marked {
+                runtime.Gosched() // In order not to fight with the GC
+                goto restart
+            }
+        }
+        succs[i] = curr
+        if found == -1 && curr.equal(key) {
+            found = i
+        }
+    }
+    return found
+}
+
+func (set *DataSet[K, V]) optimistic_left_search(key K) *node[K, V] {
+    pred := set.head
+    for i := int(pred.toplevel - 1); i >= 0; i-- {
+        curr := pred.next[i]
+        for curr.less(key) {
+            pred = curr
+            curr = pred.next[i]
+        }
+        if curr.equal(key) {
+            return curr
+        }
+    }
+    return nil
+}
+
+func (set *DataSet[K, V]) unlock_levels(nodes []*node[K, V], highestlevel uint) {
+    var old *node[K, V] = nil
+    for i := uint(0); i <= highestlevel; i++ {
+        if (old != nodes[i]) {
+            nodes[i].lock.Unlock()
+        }
+        old = nodes[i]
+    }
+}
+
+func scan[K cmp.Ordered, V any](node *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the fully linked, unmarked nodes of level 0 from 'node' to the tail, until it returns false
+    for node.bound != share.BOUND_MAX {
+        if p := node.value(); p != nil && !fn(node.key, *p) {
+            return
+        }
+        node = node.next[0]
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of level 0 of key not less than 'key' (greater if 'strict'), deleted or not
+    pred := set.head
+    var curr *node[K, V]
+    for i := int(set.head.toplevel - 1); i >= 0; i-- {
+        curr = pred.next[i]
+        for curr.less(key) || (strict && curr.equal(key)) {
+            pred = curr
+            curr = pred.next[i]
+        }
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) (*node[K, V], *V) { // First live node of level 0 from 'curr' and its value, nil if none
+    for curr.bound != share.BOUND_MAX {
+        if p := curr.value(); p != nil {
+            return curr, p
+        }
+        curr = curr.next[0]
+    }
+    return nil, nil
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) (*node[K, V], *V) { // Last live node among the first nodes satisfying 'in' and its value, nil if none
+    for {
+        pred := set.head
+        for i := int(set.head.toplevel - 1); i >= 0; i-- {
+            curr := pred.next[i]
+            for curr.bound == share.BOUND_NONE && in(curr) {
+                pred = curr
+                curr = pred.next[i]
+            }
+        }
+        if pred.bound != share.BOUND_NONE {
+            return nil, nil
+        }
+        if p := pred.value(); p != nil {
+            return pred, p
+        }
+        bound := pred.key // Deleted: look for the last node before it
+        in = func(n *node[K, V]) bool { return n.key < bound }
+    }
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V], p *V) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, *p, true
+}
+
+func (set *DataSet[K, V]) unlink(key K, node_todel *node[K, V]) { // Physical deletion of a node, marked and locked by the caller (unlocked on return)
+    var succs, preds [herlihy_max_level]*node[K, V]
+    toplevel := int(node_todel.toplevel)
+    backoff := uint(1)
+
+    for {
+        set.optimistic_search(key, preds[:], succs[:])
+
+        highest_locked := -1
+        var prev_pred *node[K, V] = nil
+        valid := true
+        for i := int(0); valid && (i < toplevel); i++ {
+            pred := preds[i]
+            succ := succs[i]
+            if pred != prev_pred {
+                pred.lock.Lock()
+                highest_locked = int(i)
+                prev_pred = pred
+            }
+            valid = !pred.marked && pred.next[i] == succ
+        }
+
+        if !valid {
+            set.unlock_levels(preds[:], uint(highest_locked))
+            if (backoff > 5000) {
+                runtime.Gosched() // Rough approximation of: nop_rep(backoff & MAX_BACKOFF)
+            }
+            backoff <<= 1
+            continue
+        }
+
+        for i := int(toplevel - 1); i >= 0; i-- {
+            preds[i].next[i] = node_todel.next[i]
+        }
+
+        node_todel.lock.Unlock()
+        set.unlock_levels(preds[:], uint(highest_locked))
+        return
+    }
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    var succs [herlihy_max_level]*node[K, V]
+    var zero V
+    for {
+        found := set.optimistic_search(key, nil, succs[:])
+        if found == -1 {
+            val, action := fn(zero, false)
+            if action != share.ACTION_STORE {
+                return zero, false
+            }
+            if set.Insert(key, val) {
+                return val, true
+            }
+            continue
+        }
+        node_found := succs[found]
+        for !volatile.ReadBool(&node_found.fullylinked) {
+            runtime.Gosched()
+        }
+        node_found.lock.Lock()
+        if node_found.marked { // Being deleted: retry once unlinked
+            node_found.lock.Unlock()
+            runtime.Gosched()
+            continue
+        }
+        p := node_found.ref.Load()
+        val, action := fn(*p, true)
+        switch action {
+        case share.ACTION_STORE:
+            node_found.ref.Store(&val)
+            node_found.lock.Unlock()
+            return val, true
+        case share.ACTION_DELETE:
+            node_found.marked = true
+            set.unlink(key, node_found)
+            set.count.Add(-1)
+            return zero, false
+        default:
+            node_found.lock.Unlock()
+            return *p, true
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax and AtomicSize
+    level_max := share.Or_default(opts.LevelMax, share.DEFAULT_LEVEL_MAX)
+    if level_max > herlihy_max_level {
+        return nil, share.Invalid_option("skiplist_herlihy_lb", "maximum level", level_max, fmt.Sprintf("at most %v", herlihy_max_level))
+    }
+    init_rand_level()
+    set := new(DataSet[K, V])
+    set.level_max = level_max
+    max := new_sentinel[K, V](share.BOUND_MAX, nil, level_max)
+    min := new_sentinel(share.BOUND_MIN, max, level_max)
+    max.fullylinked = true
+    min.fullylinked = true
+    set.head = min
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    node := set.head.next[0] // We have at least 2 elements
+    for node.next[0] != nil {
+        if (node.fullylinked && !node.marked) {
+            size++
+        }
+        node = node.next[0]
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    nd := set.optimistic_left_search(key)
+    if nd != nil {
+        if p := nd.value(); p != nil {
+            return *p, true
+        }
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var succs, preds [herlihy_max_level]*node[K, V]
+
+    toplevel := get_rand_level(set.level_max)
+    backoff := uint(1)
+
+    for {
+        found := set.optimistic_search(key, preds[:], succs[:])
+        if (found != -1) {
+            node_found := succs[found]
+            if (!node_found.marked) {
+                for (!volatile.ReadBool(&node_found.fullylinked)) {
+                    runtime.Gosched()
+                }
+                return false
+            }
+            continue
+        }
+
+        highest_locked := -1
+        var prev_pred *node[K, V] = nil
+        valid := true
+        for i := uint(0); valid && (i < toplevel); i++ {
+            pred := preds[i]
+            succ := succs[i]
+            if pred != prev_pred {
+                pred.lock.Lock()
+                highest_locked = int(i)
+                prev_pred = pred
+            }
+            valid = !pred.marked && !succ.marked && pred.next[i] == succ
+        }
+
+        if (!valid) { // Unlock the predecessors before leaving
+            set.unlock_levels(preds[:], uint(highest_locked)) // Unlocks the global-lock in the GL case
+            if (backoff > 5000) {
+                runtime.Gosched() // Rough approximation of: nop_rep(backoff & MAX_BACKOFF)
+            }
+            backoff <<= 1
+            continue
+        }
+
+        new_node := new_simple_node(key, val, uint32(toplevel))
+
+        for i := uint(0); i < toplevel; i++ {
+            new_node.next[i] = succs[i]
+        }
+
+        for i := uint(0); i < toplevel; i++ {
+            preds[i].next[i] = new_node
+        }
+
+        new_node.fullylinked = true
+
+        set.unlock_levels(preds[:], uint(highest_locked))
+        set.count.Add(1)
+        return true
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    var succs [herlihy_max_level]*node[K, V]
+
+    found := set.optimistic_search(key, nil, succs[:])
+
+    /* If not marked and ok to delete, then mark it */
+    if found == -1 || !ok_to_delete(succs[found], found) {
+        var zero V
+        return zero, false
+    }
+
+    node_todel := succs[found]
+    node_todel.lock.Lock()
+
+    /* Unless it has been marked meanfor */
+    if (node_todel.marked) {
+        node_todel.lock.Unlock()
+        var zero V
+        return zero, false
+    }
+
+    node_todel.marked = true
+    val := *node_todel.ref.Load()
+
+    /* Physical deletion */
+    set.unlink(key, node_todel)
+    set.count.Add(-1)
+    return val, true
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: link the nodes in order at each of their levels, see 'share.Bulk_level'
+    if set.Size() != 0 {
+        return share.Invalid_load("skiplist_herlihy_lb", "the set is not empty")
+    }
+    if err := share.Check_sorted("skiplist_herlihy_lb", pairs); err != nil {
+        return err
+    }
+    var last [herlihy_max_level]*node[K, V] // Last node linked at each level
+    tail := set.head.next[0]
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl] = set.head
+    }
+    for i, pair := range pairs {
+        toplevel := share.Bulk_level(uint(i + 1), set.level_max)
+        node := new_simple_node(pair.Key, pair.Val, uint32(toplevel))
+        node.fullylinked = true
+        for lvl := uint(0); lvl < toplevel; lvl++ {
+            last[lvl].next[lvl] = node
+            last[lvl] = node
+        }
+    }
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl].next[lvl] = tail
+    }
+    set.count.Add(int64(len(pairs)))
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent and lock-free, like 'Find'
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        scan(set.head.next[0], yield)
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(set.head.next[0]))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
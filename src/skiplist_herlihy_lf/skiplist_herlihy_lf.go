@@ -0,0 +1,511 @@
+/**
+ * @file   skiplist_herlihy_lf.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Lock-free skip list of Herlihy and Shavit,
+ * "The Art of Multiprocessor Programming", M. Herlihy, N. Shavit, chapter 14.4, 2008.
+ *
+ * Every next pointer is a marked reference: a node is deleted by marking its
+ * next pointers from the top level down, then unlinked by the searches of the
+ * updates, which restart from the head whenever such a CAS fails. 'Find' is
+ * wait-free, skipping the marked nodes without unlinking them.
+ *
+ * Values are boxed behind an atomic pointer, so that they can be replaced with
+ * a CAS; a node is logically deleted once this pointer is cleared, before its
+ * next pointers get marked.
+**/
+
+package skiplist_herlihy_lf
+
+import (
+    "cmp"
+    "fmt"
+    "iter"
+    "sync/atomic"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+const (
+    herlihy_max_level = uint(64)
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced, nil once (logically) deleted
+    bound share.Bound
+    toplevel uint32
+    next []*node[K, V]
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    level_max uint
+    head *node[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+var state xorshift.State
+
+func init_rand_level() {
+    state.Init()
+}
+
+func get_rand_level(level_max uint) uint {
+    level := uint(1)
+    for i := uint(0); i < level_max - 1; i++ {
+        if state.Intn(100) < 50 {
+            level++
+        } else {
+            break
+        }
+    }
+    return level
+}
+
+// -----------------------------------------------------------------------------
+
+func is_marked[K cmp.Ordered, V any](i *node[K, V]) bool {
+    return (uintptr(unsafe.Pointer(i)) & 1) != 0
+}
+
+func unset_mark[K cmp.Ordered, V any](i *node[K, V]) *node[K, V] {
+    if !is_marked(i) {
+        return i
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(i), -1)) // Stay in unsafe.Add, so the GC keeps seeing a pointer
+}
+
+func set_mark[K cmp.Ordered, V any](i *node[K, V]) *node[K, V] {
+    if is_marked(i) {
+        return i
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(i), 1))
+}
+
+func cas_next[K cmp.Ordered, V any](n *node[K, V], level uint32, old *node[K, V], new *node[K, V]) bool {
+    return atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&n.next[level])), unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) value() *V { // Current value, nil if logically deleted
+    return n.ref.Load()
+}
+
+func new_simple_node[K cmp.Ordered, V any](key K, val V, toplevel uint32, level_max uint) *node[K, V] {
+    elem := new(node[K, V])
+    elem.key = key
+    elem.val = val
+    elem.ref.Store(&elem.val)
+    elem.toplevel = toplevel
+    elem.next = make([]*node[K, V], level_max)
+    return elem
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V], toplevel uint32, level_max uint) *node[K, V] {
+    node := new_simple_node(key, val, toplevel, level_max)
+    for i := uint(0); i < level_max; i++ {
+        node.next[i] = next
+    }
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V], level_max uint) *node[K, V] {
+    var key K
+    var val V
+    elem := new_node(key, val, next, uint32(level_max), level_max)
+    elem.bound = bound
+    return elem
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) find(key K, preds []*node[K, V], succs []*node[K, V]) bool { // Predecessors and first nodes not less than 'key' at each level, unlinking each marked node met (restarting on failure); whether the key is linked at level 0
+retry:
+    pred := set.head
+    var curr *node[K, V]
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        curr = unset_mark(pred.next[i])
+        for {
+            succ := curr.next[i]
+            for is_marked(succ) { // Snip 'curr'
+                if !cas_next(pred, uint32(i), curr, unset_mark(succ)) {
+                    goto retry
+                }
+                curr = unset_mark(succ)
+                succ = curr.next[i]
+            }
+            if !curr.less(key) {
+                break
+            }
+            pred = curr
+            curr = succ
+        }
+        if preds != nil {
+            preds[i] = pred
+        }
+        if succs != nil {
+            succs[i] = curr
+        }
+    }
+    return curr.equal(key)
+}
+
+func mark_node_ptrs[K cmp.Ordered, V any](n *node[K, V]) { // Mark the next pointers of 'n' from its top level down, so that nothing gets linked after it anymore
+    for i := int(n.toplevel - 1); i >= 0; i-- {
+        for {
+            n_next := n.next[i]
+            if is_marked(n_next) || cas_next(n, uint32(i), n_next, set_mark(n_next)) {
+                break
+            }
+        }
+    }
+}
+
+func (set *DataSet[K, V]) link(n *node[K, V], preds []*node[K, V], succs []*node[K, V]) { // Link the node 'n', already linked at level 0, at its upper levels; give up once it gets marked
+    for i := uint32(1); i < n.toplevel; i++ {
+        for {
+            succ := succs[i]
+            n_next := n.next[i]
+            if is_marked(n_next) {
+                return
+            }
+            if n_next != succ && !cas_next(n, i, n_next, succ) { // Stale successor, as the search was restarted
+                return
+            }
+            if cas_next(preds[i], i, succ, n) {
+                break
+            }
+            if !set.find(n.key, preds, succs) || succs[0] != n { // Deleted meanwhile
+                return
+            }
+        }
+    }
+}
+
+func scan[K cmp.Ordered, V any](node *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the non-deleted nodes of level 0 from 'node' to the tail, until it returns false
+    for node.bound != share.BOUND_MAX {
+        if p := node.value(); p != nil && !fn(node.key, *p) {
+            return
+        }
+        node = unset_mark(node.next[0])
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of level 0 of key not less than 'key' (greater if 'strict'), deleted or not
+    pred := set.head
+    var curr *node[K, V]
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        curr = unset_mark(pred.next[i])
+        for curr.less(key) || (strict && curr.equal(key)) {
+            pred = curr
+            curr = unset_mark(pred.next[i])
+        }
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) (*node[K, V], *V) { // First live node of level 0 from 'curr' and its value, nil if none
+    for curr.bound != share.BOUND_MAX {
+        if p := curr.value(); p != nil {
+            return curr, p
+        }
+        curr = unset_mark(curr.next[0])
+    }
+    return nil, nil
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) (*node[K, V], *V) { // Last live node among the first nodes satisfying 'in' and its value, nil if none
+    for {
+        pred := set.head
+        for i := int(set.level_max - 1); i >= 0; i-- {
+            curr := unset_mark(pred.next[i])
+            for curr.bound == share.BOUND_NONE && in(curr) {
+                pred = curr
+                curr = unset_mark(pred.next[i])
+            }
+        }
+        if pred.bound != share.BOUND_NONE {
+            return nil, nil
+        }
+        if p := pred.value(); p != nil {
+            return pred, p
+        }
+        bound := pred.key // Deleted: look for the last node before it
+        in = func(n *node[K, V]) bool { return n.key < bound }
+    }
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V], p *V) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, *p, true
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    var succs [herlihy_max_level]*node[K, V]
+    var zero V
+    for {
+        if !set.find(key, nil, succs[:]) {
+            val, action := fn(zero, false)
+            if action != share.ACTION_STORE {
+                return zero, false
+            }
+            if set.Insert(key, val) {
+                return val, true
+            }
+            continue
+        }
+        node := succs[0]
+        p := node.value()
+        if p == nil { // Value is deleted: remove it and retry
+            mark_node_ptrs(node)
+            continue
+        }
+        val, action := fn(*p, true)
+        switch action {
+        case share.ACTION_STORE:
+            if node.ref.CompareAndSwap(p, &val) {
+                return val, true
+            }
+        case share.ACTION_DELETE:
+            if node.ref.CompareAndSwap(p, nil) {
+                set.count.Add(-1)
+                mark_node_ptrs(node)
+                set.find(key, nil, nil)
+                return zero, false
+            }
+        default:
+            return *p, true
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax and AtomicSize
+    level_max := share.Or_default(opts.LevelMax, share.DEFAULT_LEVEL_MAX)
+    if level_max > herlihy_max_level {
+        return nil, share.Invalid_option("skiplist_herlihy_lf", "maximum level", level_max, fmt.Sprintf("at most %v", herlihy_max_level))
+    }
+    init_rand_level()
+    set := new(DataSet[K, V])
+    set.level_max = level_max
+    max := new_sentinel[K, V](share.BOUND_MAX, nil, level_max)
+    min := new_sentinel(share.BOUND_MIN, max, level_max)
+    set.head = min
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    node := unset_mark(set.head.next[0])
+    for node.next[0] != nil {
+        if node.value() != nil {
+            size++
+        }
+        node = unset_mark(node.next[0])
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) { // Wait-free, marked nodes being skipped (but not unlinked)
+    pred := set.head
+    var curr *node[K, V]
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        curr = unset_mark(pred.next[i])
+        for {
+            succ := curr.next[i]
+            for is_marked(succ) {
+                curr = unset_mark(succ)
+                succ = curr.next[i]
+            }
+            if !curr.less(key) {
+                break
+            }
+            pred = curr
+            curr = succ
+        }
+    }
+    if curr.equal(key) {
+        if p := curr.value(); p != nil {
+            return *p, true
+        }
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var succs, preds [herlihy_max_level]*node[K, V]
+    toplevel := uint32(get_rand_level(set.level_max))
+    for {
+        if set.find(key, preds[:], succs[:]) {
+            if succs[0].value() == nil { // Value is deleted: remove it and retry
+                mark_node_ptrs(succs[0])
+                continue
+            }
+            return false
+        }
+        new_node := new_simple_node(key, val, toplevel, set.level_max)
+        for i := uint32(0); i < toplevel; i++ {
+            new_node.next[i] = succs[i]
+        }
+        /* Node is visible once inserted at lowest level */
+        if !cas_next(preds[0], 0, succs[0], new_node) {
+            continue
+        }
+        set.count.Add(1)
+        set.link(new_node, preds[:], succs[:])
+        return true
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    var succs [herlihy_max_level]*node[K, V]
+    var zero V
+    if !set.find(key, nil, succs[:]) {
+        return zero, false
+    }
+    node := succs[0]
+    /* 1. Node is logically deleted when the value field is nil */
+    for {
+        p := node.value()
+        if p == nil {
+            return zero, false
+        }
+        if node.ref.CompareAndSwap(p, nil) {
+            set.count.Add(-1)
+            /* 2. Mark forward pointers, then the search unlinks the node */
+            mark_node_ptrs(node)
+            set.find(key, nil, nil)
+            return *p, true
+        }
+    }
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: link the nodes in order at each of their levels, see 'share.Bulk_level'
+    if set.Size() != 0 {
+        return share.Invalid_load("skiplist_herlihy_lf", "the set is not empty")
+    }
+    if err := share.Check_sorted("skiplist_herlihy_lf", pairs); err != nil {
+        return err
+    }
+    var last [herlihy_max_level]*node[K, V] // Last node linked at each level
+    tail := unset_mark(set.head.next[0])
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl] = set.head
+    }
+    for i, pair := range pairs {
+        toplevel := share.Bulk_level(uint(i + 1), set.level_max)
+        node := new_simple_node(pair.Key, pair.Val, uint32(toplevel), set.level_max)
+        for lvl := uint(0); lvl < toplevel; lvl++ {
+            last[lvl].next[lvl] = node
+            last[lvl] = node
+        }
+    }
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl].next[lvl] = tail
+    }
+    set.count.Add(int64(len(pairs)))
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent and read-only, like 'Find'
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        scan(unset_mark(set.head.next[0]), yield)
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(unset_mark(set.head.next[0])))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
@@ -0,0 +1,236 @@
+/**
+ * @file   skiplist_lock.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Global-lock baseline: the sequential skip list ('skiplist_seq') behind a
+ * single 'sync.Mutex', or a 'sync.RWMutex' whose searches share the lock. As
+ * every operation holds the lock for its whole duration, the scans are atomic,
+ * but their callbacks must not access the skip list.
+**/
+
+package skiplist_lock
+
+import (
+    "cmp"
+    "iter"
+    "sync"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/skiplist_seq"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+// -----------------------------------------------------------------------------
+
+type DataSet[K cmp.Ordered, V any] struct {
+    lock sync.Locker                  // Held by the updates
+    rlock sync.Locker                 // Held by the searches, shared among them for a 'sync.RWMutex'
+    set *skiplist_seq.DataSet[K, V]
+    count *counter.Striped            // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func new_set[K cmp.Ordered, V any](opts share.Options, lock sync.Locker, rlock sync.Locker) (*DataSet[K, V], error) {
+    inner, err := skiplist_seq.New[K, V](opts)
+    if err != nil {
+        return nil, err
+    }
+    set := new(DataSet[K, V])
+    set.lock = lock
+    set.rlock = rlock
+    set.set = inner
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax and AtomicSize, with a 'sync.Mutex'
+    lock := new(sync.Mutex)
+    return new_set[K, V](opts, lock, lock)
+}
+
+func NewRW[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax and AtomicSize, with a 'sync.RWMutex'
+    lock := new(sync.RWMutex)
+    return new_set[K, V](opts, lock, lock.RLocker())
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Size()
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Find(key)
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    if !set.set.Insert(key, val) {
+        return false
+    }
+    set.count.Add(1)
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    val, ok := set.set.Delete(key)
+    if ok {
+        set.count.Add(-1)
+    }
+    return val, ok
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    var res []dataset.Pair[K, V]
+    for key, val := range set.set.All() {
+        res = append(res, dataset.Pair[K, V]{Key: key, Val: val})
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    if err := set.set.BulkLoad(pairs); err != nil {
+        return err
+    }
+    set.count.Add(int64(len(pairs))) // Strictly increasing keys
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    old, ok := set.set.Put(key, val)
+    if !ok {
+        set.count.Add(1)
+    }
+    return old, ok
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    return set.set.CompareAndSwap(key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    res, loaded := set.set.LoadOrStore(key, val)
+    if !loaded {
+        set.count.Add(1)
+    }
+    return res, loaded
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    var found bool
+    val, ok := set.set.Compute(key, func(cur V, ok bool) (V, bool) {
+        found = ok
+        return fn(cur, ok)
+    })
+    if ok && !found {
+        set.count.Add(1)
+    } else if !ok && found {
+        set.count.Add(-1)
+    }
+    return val, ok
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Atomic, the lock being held during the whole scan
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    set.set.Range(lo, hi, fn)
+}
+
+func (set *DataSet[K, V]) RangeAtomic(lo K, hi K, fn func(key K, val V) bool) {
+    set.Range(lo, hi, fn)
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        set.rlock.Lock()
+        defer set.rlock.Unlock()
+        for key, val := range set.set.All() {
+            if !yield(key, val) {
+                return
+            }
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Min()
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Max()
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Ceiling(key)
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Floor(key)
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Successor(key)
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    set.rlock.Lock()
+    defer set.rlock.Unlock()
+    return set.set.Predecessor(key)
+}
@@ -0,0 +1,625 @@
+/**
+ * @file   skiplist_nohotspot.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * No hot spot non-blocking skip list,
+ * T. Crain, V. Gramoli, M. Raynal,
+ * ICDCS 2013.
+ *
+ * The updates only touch the bottom level, a lock-free sorted list: an insert
+ * links a new node (or revives a deleted one), a delete only clears the value.
+ * The index levels, made of index items above the bottom nodes, belong to a
+ * background goroutine. Every 'maintenance_period', it traverses the bottom
+ * level, unlinking the deleted nodes without index item and raising one node
+ * out of two (among three consecutive nodes at the same height), then does
+ * the same with each index level: index items of deleted nodes are unlinked,
+ * and one item out of two is raised to the level above. Index levels are added
+ * (up to 'LevelMax' levels in total) and removed as the top one fills or empties.
+ *
+ * A node is physically removed in three steps: the background goroutine claims
+ * its (cleared) value, so that it cannot be revived anymore, marks its next
+ * pointer, then unlinks it. The updates help with the last step when they meet
+ * a marked node, restarting from the head whenever this CAS fails.
+ *
+ * The index is only a hint: a search starts from the bottom node reached
+ * through it, or from the head if this node is being removed. 'Destroy' stops
+ * the background goroutine; without it, the skip list is never reclaimed.
+**/
+
+package skiplist_nohotspot
+
+import (
+    "cmp"
+    "fmt"
+    "iter"
+    "sync"
+    "sync/atomic"
+    "time"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    nohotspot_max_level = uint(64)
+    maintenance_period = 100 * time.Microsecond // Pause between two passes of the background goroutine
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced, nil once (logically) deleted, 'removed' once claimed for removal
+    bound share.Bound
+    level uint            // Amount of index levels above the node (background goroutine only)
+    next *node[K, V]
+}
+
+type index[K cmp.Ordered, V any] struct {
+    node *node[K, V]
+    down *index[K, V]     // Item of the level below, nil at the first index level
+    right atomic.Pointer[index[K, V]]
+    unlinked bool         // Unlinked from its level (background goroutine only)
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    level_max uint
+    head *node[K, V]
+    heads []*index[K, V]  // Head item of each index level, from the lowest one (background goroutine only)
+    top atomic.Pointer[index[K, V]] // Head item of the top index level
+    removed *V            // Value of the nodes claimed for removal
+    maintenance sync.Mutex // Held during each pass of the background goroutine
+    stop chan struct{}    // Closed by 'Destroy'
+    stopped sync.Once     // So that 'Destroy' closes 'stop' once
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+func is_marked[K cmp.Ordered, V any](i *node[K, V]) bool {
+    return (uintptr(unsafe.Pointer(i)) & 1) != 0
+}
+
+func unset_mark[K cmp.Ordered, V any](i *node[K, V]) *node[K, V] {
+    if !is_marked(i) {
+        return i
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(i), -1)) // Stay in unsafe.Add, so the GC keeps seeing a pointer
+}
+
+func set_mark[K cmp.Ordered, V any](i *node[K, V]) *node[K, V] {
+    if is_marked(i) {
+        return i
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(i), 1))
+}
+
+func cas_next[K cmp.Ordered, V any](n *node[K, V], old *node[K, V], new *node[K, V]) bool {
+    return atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&n.next)), unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+func mark_next[K cmp.Ordered, V any](n *node[K, V]) { // Mark the next pointer of 'n', so that nothing gets inserted after it
+    for {
+        next := n.next
+        if is_marked(next) || cas_next(n, next, set_mark(next)) {
+            return
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (set *DataSet[K, V]) value(n *node[K, V]) *V { // Current value, nil if logically deleted
+    p := n.ref.Load()
+    if p == set.removed {
+        return nil
+    }
+    return p
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V]) *node[K, V] {
+    node := new(node[K, V])
+    node.key = key
+    node.val = val
+    node.ref.Store(&node.val)
+    node.next = next
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V]) *node[K, V] {
+    var key K
+    var val V
+    node := new_node(key, val, next)
+    node.bound = bound
+    return node
+}
+
+func new_index[K cmp.Ordered, V any](node *node[K, V], down *index[K, V]) *index[K, V] {
+    item := new(index[K, V])
+    item.node = node
+    item.down = down
+    return item
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) start(before func(n *node[K, V]) bool) *node[K, V] { // Last node satisfying 'before' reached through the index, or the head if this node is being removed
+    item := set.top.Load()
+    for {
+        for right := item.right.Load(); right != nil && before(right.node); right = item.right.Load() {
+            item = right
+        }
+        if item.down == nil {
+            break
+        }
+        item = item.down
+    }
+    if is_marked(item.node.next) {
+        return set.head
+    }
+    return item.node
+}
+
+func (set *DataSet[K, V]) locate(key K) (*node[K, V], *node[K, V]) { // Unmarked predecessor and first unmarked node not less than 'key', unlinking each marked node met, restarting on failure
+retry:
+    for {
+        pred := set.start(func(n *node[K, V]) bool { return n.less(key) })
+        curr := unset_mark(pred.next)
+        for {
+            next := curr.next
+            if is_marked(next) {
+                if !cas_next(pred, curr, unset_mark(next)) {
+                    continue retry
+                }
+                curr = unset_mark(next)
+                continue
+            }
+            if !curr.less(key) {
+                return pred, curr
+            }
+            pred = curr
+            curr = next
+        }
+    }
+}
+
+func (set *DataSet[K, V]) scan(node *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the non-deleted nodes from 'node' to the tail, until it returns false
+    for node.bound != share.BOUND_MAX {
+        if p := set.value(node); p != nil && !fn(node.key, *p) {
+            return
+        }
+        node = unset_mark(node.next)
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of key not less than 'key' (greater if 'strict'), deleted or not
+    before := func(n *node[K, V]) bool { return n.less(key) || (strict && n.equal(key)) }
+    curr := unset_mark(set.start(before).next)
+    for before(curr) {
+        curr = unset_mark(curr.next)
+    }
+    return curr
+}
+
+func (set *DataSet[K, V]) first(curr *node[K, V]) (*node[K, V], *V) { // First live node from 'curr' and its value, nil if none
+    for curr.bound != share.BOUND_MAX {
+        if p := set.value(curr); p != nil {
+            return curr, p
+        }
+        curr = unset_mark(curr.next)
+    }
+    return nil, nil
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) (*node[K, V], *V) { // Last live node among the first nodes satisfying 'in' and its value, nil if none
+    start := set.start(func(n *node[K, V]) bool { return n.bound == share.BOUND_NONE && in(n) })
+    for {
+        var res *node[K, V] = nil
+        var res_val *V = nil
+        curr := start
+        if curr == set.head {
+            curr = unset_mark(curr.next)
+        }
+        for curr.bound != share.BOUND_MAX && in(curr) {
+            if p := set.value(curr); p != nil {
+                res, res_val = curr, p
+            }
+            curr = unset_mark(curr.next)
+        }
+        if res != nil || start == set.head {
+            return res, res_val
+        }
+        start = set.head // Every node reached is deleted: look again from the head
+    }
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V], p *V) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, *p, true
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    var zero V
+    for {
+        pred, curr := set.locate(key)
+        if !curr.equal(key) {
+            val, action := fn(zero, false)
+            if action != share.ACTION_STORE {
+                return zero, false
+            }
+            if cas_next(pred, curr, new_node(key, val, curr)) {
+                set.count.Add(1)
+                return val, true
+            }
+            continue
+        }
+        p := curr.ref.Load()
+        if p == set.removed { // Being removed: help marking it, so that it gets unlinked, then retry
+            mark_next(curr)
+            continue
+        }
+        if p == nil { // Deleted: revive the node
+            val, action := fn(zero, false)
+            if action != share.ACTION_STORE {
+                return zero, false
+            }
+            if curr.ref.CompareAndSwap(nil, &val) {
+                set.count.Add(1)
+                return val, true
+            }
+            continue
+        }
+        val, action := fn(*p, true)
+        switch action {
+        case share.ACTION_STORE:
+            if curr.ref.CompareAndSwap(p, &val) {
+                return val, true
+            }
+        case share.ACTION_DELETE:
+            if curr.ref.CompareAndSwap(p, nil) {
+                set.count.Add(-1)
+                return zero, false
+            }
+        default:
+            return *p, true
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) remove(pred *node[K, V], n *node[K, V]) (bool, bool) { // Physically remove the deleted node 'n', following 'pred'; whether it was claimed (i.e. not revived meanwhile), and unlinked
+    if !n.ref.CompareAndSwap(nil, set.removed) {
+        return false, false
+    }
+    mark_next(n)
+    return true, cas_next(pred, n, unset_mark(n.next)) // On failure, the next traversal unlinks it
+}
+
+func (set *DataSet[K, V]) add_level() *index[K, V] { // Add an index level on top of the others, return its head item
+    head := new_index(set.head, set.heads[len(set.heads) - 1])
+    set.heads = append(set.heads, head)
+    set.top.Store(head)
+    return head
+}
+
+func (set *DataSet[K, V]) raise_bottom_level() { // Unlink the deleted nodes without index item, and raise the nodes between two nodes of height 0
+    above := set.heads[0]
+    pred := set.head
+    curr := unset_mark(pred.next)
+    for curr.bound != share.BOUND_MAX {
+        next := curr.next
+        if is_marked(next) { // Removed, but not unlinked yet
+            if !cas_next(pred, curr, unset_mark(next)) {
+                return // 'pred' is removed or got a new successor, leave the rest for the next pass
+            }
+            curr = unset_mark(next)
+            continue
+        }
+        if curr.level == 0 {
+            if claimed, unlinked := set.remove(pred, curr); unlinked {
+                curr = unset_mark(curr.next)
+                continue
+            } else if claimed {
+                return // 'pred' is removed or got a new successor, leave the rest for the next pass
+            }
+            if pred.level == 0 && pred.bound == share.BOUND_NONE && next.level == 0 {
+                for right := above.right.Load(); right != nil && right.node.key < curr.key; right = above.right.Load() {
+                    above = right
+                }
+                item := new_index(curr, nil)
+                item.right.Store(above.right.Load())
+                above.right.Store(item)
+                above = item
+                curr.level = 1
+            }
+        }
+        pred = curr
+        curr = next
+    }
+}
+
+func (set *DataSet[K, V]) raise_index_level(level uint) { // Unlink the items of deleted nodes (or above unlinked items) from an index level (from 1), and raise the items between two items of the same height
+    var above *index[K, V]
+    if level < uint(len(set.heads)) {
+        above = set.heads[level]
+    }
+    pred := set.heads[level - 1]
+    for curr := pred.right.Load(); curr != nil; curr = pred.right.Load() {
+        next := curr.right.Load()
+        if set.value(curr.node) == nil || (curr.down != nil && curr.down.unlinked) {
+            pred.right.Store(next)
+            curr.unlinked = true
+            curr.node.level = min(curr.node.level, level - 1)
+            continue
+        }
+        if curr.node.level == level && pred != set.heads[level - 1] && pred.node.level == level && (next == nil || next.node.level == level) {
+            if above == nil && level + 1 < set.level_max {
+                above = set.add_level()
+            }
+            if above != nil {
+                for right := above.right.Load(); right != nil && right.node.key < curr.node.key; right = above.right.Load() {
+                    above = right
+                }
+                item := new_index(curr.node, curr)
+                item.right.Store(above.right.Load())
+                above.right.Store(item)
+                above = item
+                curr.node.level = level + 1
+            }
+        }
+        pred = curr
+    }
+}
+
+func (set *DataSet[K, V]) maintain() { // One pass of the background goroutine
+    set.maintenance.Lock()
+    defer set.maintenance.Unlock()
+    set.raise_bottom_level()
+    for level := uint(1); level <= uint(len(set.heads)); level++ {
+        set.raise_index_level(level)
+    }
+    for len(set.heads) > 1 && set.heads[len(set.heads) - 1].right.Load() == nil { // Lower the empty top levels
+        set.heads = set.heads[:len(set.heads) - 1]
+        set.top.Store(set.heads[len(set.heads) - 1])
+    }
+}
+
+func (set *DataSet[K, V]) background() {
+    ticker := time.NewTicker(maintenance_period)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-set.stop:
+            return
+        case <-ticker.C:
+            set.maintain()
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax and AtomicSize
+    level_max := share.Or_default(opts.LevelMax, share.DEFAULT_LEVEL_MAX)
+    if level_max < 2 || level_max > nohotspot_max_level {
+        return nil, share.Invalid_option("skiplist_nohotspot", "maximum level", level_max, fmt.Sprintf("between 2 and %v", nohotspot_max_level))
+    }
+    set := new(DataSet[K, V])
+    set.level_max = level_max
+    max := new_sentinel[K, V](share.BOUND_MAX, nil)
+    min := new_sentinel(share.BOUND_MIN, max)
+    set.head = min
+    set.heads = []*index[K, V]{new_index(min, nil)}
+    set.top.Store(set.heads[0])
+    set.removed = share.New_sentinel[V]()
+    set.stop = make(chan struct{})
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    go set.background()
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() { // Idempotent
+    set.stopped.Do(func() { close(set.stop) })
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    for range set.All() {
+        size++
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) { // Read-only, marked nodes being skipped (but not unlinked)
+    curr := set.search(key, false)
+    if curr.equal(key) {
+        if p := set.value(curr); p != nil {
+            return *p, true
+        }
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    for {
+        pred, curr := set.locate(key)
+        if !curr.equal(key) {
+            if cas_next(pred, curr, new_node(key, val, curr)) {
+                set.count.Add(1)
+                return true
+            }
+            continue
+        }
+        p := curr.ref.Load()
+        if p == set.removed { // Being removed: help marking it, so that it gets unlinked, then retry
+            mark_next(curr)
+            continue
+        }
+        if p != nil {
+            return false
+        }
+        if curr.ref.CompareAndSwap(nil, &val) { // Deleted: revive the node
+            set.count.Add(1)
+            return true
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) { // Logical only, the node being removed by the background goroutine
+    curr := set.search(key, false)
+    var zero V
+    if !curr.equal(key) {
+        return zero, false
+    }
+    for {
+        p := set.value(curr)
+        if p == nil {
+            return zero, false
+        }
+        if curr.ref.CompareAndSwap(p, nil) {
+            set.count.Add(-1)
+            return *p, true
+        }
+    }
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: link the nodes in order at the bottom level, and their index items at each of their levels, see 'share.Bulk_level'
+    set.maintenance.Lock()
+    defer set.maintenance.Unlock()
+    if set.Size() != 0 {
+        return share.Invalid_load("skiplist_nohotspot", "the set is not empty")
+    }
+    if err := share.Check_sorted("skiplist_nohotspot", pairs); err != nil {
+        return err
+    }
+    tail := unset_mark(set.head.next)
+    for tail.bound != share.BOUND_MAX { // Deleted nodes, not removed yet
+        tail = unset_mark(tail.next)
+    }
+    var last [nohotspot_max_level]*index[K, V] // Last item linked at each index level
+    var first [nohotspot_max_level]*index[K, V]
+    var prev *node[K, V] = nil
+    head := tail
+    for i, pair := range pairs {
+        node := new_node(pair.Key, pair.Val, tail)
+        if prev == nil {
+            head = node
+        } else {
+            prev.next = node
+        }
+        prev = node
+        node.level = share.Bulk_level(uint(i + 1), set.level_max) - 1
+        for uint(len(set.heads)) < node.level {
+            set.add_level()
+        }
+        var down *index[K, V] = nil
+        for lvl := uint(0); lvl < node.level; lvl++ {
+            item := new_index(node, down)
+            if last[lvl] == nil {
+                first[lvl] = item
+            } else {
+                last[lvl].right.Store(item)
+            }
+            last[lvl] = item
+            down = item
+        }
+    }
+    for lvl, head := range set.heads { // Also drop the items of the deleted nodes
+        head.right.Store(first[lvl])
+    }
+    set.head.next = head
+    set.count.Add(int64(len(pairs)))
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent and read-only, like 'Find'
+    set.scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        set.scan(unset_mark(set.head.next), yield)
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(set.first(unset_mark(set.head.next)))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(set.first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(set.first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
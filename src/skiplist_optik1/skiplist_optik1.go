@@ -0,0 +1,676 @@
+/**
+ * @file   skiplist_optik1.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * A skip-list algorithm design with OPTIK.
+ * High-level description of the algorithm:
+ * - Search: Simply traverse the levels of the skip list
+ * - Parse (i.e., traverse to the point you want to modify): Traverse
+ *     and keep track of the predecessor node for the target key at each level
+ *     as well as the OPTIK version of each predecessor. Unlike other skip lists
+ *     this one does not need to keep track of successor nodes for validation.
+ *     optik_trylock_version takes care of validation.
+ * - Insert: do the parse and the start from level 0, lock with trylock_version and
+ *     insert the new node. If the trylock fails, reparse and continue from the previous
+ *     level. The state flag of a node indicates whether a node is fully linked.
+ * - Delete: parse and then try to do optik_trylock_vdelete on the node. If successful,
+ *     try to grab the lock with optik_trylock_version on all levels and then unlink
+ *     the node. If one of the trylock calls fail, release all locks and retry.
+ * - Update: parse and then replace the value of the node with optik_trylock_version
+ *     on it, so that the version of a node also covers its value.
+**/
+
+package skiplist_optik1
+
+import (
+    "cmp"
+    "fmt"
+    "iter"
+    "runtime"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/optik"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+const (
+    optik_max_level = uint(64)
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced (under the lock)
+    bound share.Bound
+    toplevel uint32
+    state uint32
+    lock optik.Mutex
+    next []*node[K, V]
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    level_max uint
+    head *node[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+var state xorshift.State
+
+func init_rand_level() {
+    state.Init()
+}
+
+func get_rand_level(level_max uint) uint {
+    level := uint(1)
+    for i := uint(0); i < level_max - 1; i++ {
+        if state.Intn(100) < 50 {
+            level++
+        } else {
+            break
+        }
+    }
+    return level
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) value() *V { // Current value, nil if logically deleted
+    if optik.Is_deleted(n.lock) {
+        return nil
+    }
+    return n.ref.Load()
+}
+
+func new_simple_node[K cmp.Ordered, V any](key K, val V, toplevel uint32, level_max uint) *node[K, V] {
+    elem := new(node[K, V])
+    elem.key = key
+    elem.val = val
+    elem.ref.Store(&elem.val)
+    elem.toplevel = toplevel
+    elem.state = 0
+    elem.lock.Init()
+    elem.next = make([]*node[K, V], level_max)
+    return elem
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V], toplevel uint32, level_max uint) *node[K, V] {
+    node := new_simple_node(key, val, toplevel, level_max)
+    for i := uint32(0); i < toplevel; i++ {
+        node.next[i] = next
+    }
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V], level_max uint) *node[K, V] {
+    var key K
+    var val V
+    elem := new_node(key, val, next, uint32(level_max), level_max)
+    elem.bound = bound
+    return elem
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[K, V]) optik_search(key K, preds []*node[K, V], predsv []optik.Mutex, node_foundv *optik.Mutex) *node[K, V] {
+restart:
+    var node_found *node[K, V] = nil
+    pred := set.head
+    predv := set.head.lock
+    for i := int(pred.toplevel - 1); i >= 0; i-- {
+        curr := pred.next[i]
+        currv := curr.lock
+        for curr.less(key) {
+            predv = currv
+            pred = curr
+            curr = pred.next[i]
+            currv = curr.lock
+        }
+        if optik.Is_deleted(predv) {
+            runtime.Gosched() // In order not to fight with the GC
+            goto restart
+        }
+        preds[i] = pred
+        predsv[i] = predv
+        if curr.equal(key) {
+            node_found = curr
+            *node_foundv = currv
+        }
+    }
+    return node_found
+}
+
+func (set *DataSet[K, V]) optik_left_search(key K) *node[K, V] {
+    pred := set.head
+    for i := int(pred.toplevel - 1); i >= 0; i-- {
+        curr := pred.next[i]
+        for curr.less(key) {
+            pred = curr
+            curr = pred.next[i]
+        }
+        if curr.equal(key) {
+            return curr
+        }
+    }
+    return nil
+}
+
+func unlock_levels_down[K cmp.Ordered, V any](nodes []*node[K, V], low int, high int) {
+    var old *node[K, V] = nil
+    for i := high; i >= low; i-- {
+        if old != nodes[i] {
+            nodes[i].lock.Unlock()
+        }
+        old = nodes[i]
+    }
+}
+
+func unlock_levels_up[K cmp.Ordered, V any](nodes []*node[K, V], low int, high int) {
+    var old *node[K, V] = nil
+    for i := low; i < high; i++ {
+        if old != nodes[i] {
+            nodes[i].lock.Unlock()
+        }
+        old = nodes[i]
+    }
+}
+
+func scan[K cmp.Ordered, V any](node *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the non-deleted nodes of level 0 from 'node' to the tail, until it returns false
+    for node.bound != share.BOUND_MAX {
+        if p := node.value(); p != nil && !fn(node.key, *p) {
+            return
+        }
+        node = node.next[0]
+    }
+}
+
+func (set *DataSet[K, V]) optik_lower_pred(key K) *node[K, V] { // Last node of level 0 less than 'key', without any version
+    pred := set.head
+    for i := int(pred.toplevel - 1); i >= 0; i-- {
+        curr := pred.next[i]
+        for curr.less(key) {
+            pred = curr
+            curr = pred.next[i]
+        }
+    }
+    return pred
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of level 0 of key not less than 'key' (greater if 'strict'), deleted or not
+    pred := set.head
+    var curr *node[K, V]
+    for i := int(set.head.toplevel - 1); i >= 0; i-- {
+        curr = pred.next[i]
+        for curr.less(key) || (strict && curr.equal(key)) {
+            pred = curr
+            curr = pred.next[i]
+        }
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) (*node[K, V], *V) { // First live node of level 0 from 'curr' and its value, nil if none
+    for curr.bound != share.BOUND_MAX {
+        if p := curr.value(); p != nil {
+            return curr, p
+        }
+        curr = curr.next[0]
+    }
+    return nil, nil
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) (*node[K, V], *V) { // Last live node among the first nodes satisfying 'in' and its value, nil if none
+    for {
+        pred := set.head
+        for i := int(set.head.toplevel - 1); i >= 0; i-- {
+            curr := pred.next[i]
+            for curr.bound == share.BOUND_NONE && in(curr) {
+                pred = curr
+                curr = pred.next[i]
+            }
+        }
+        if pred.bound != share.BOUND_NONE {
+            return nil, nil
+        }
+        if p := pred.value(); p != nil {
+            return pred, p
+        }
+        bound := pred.key // Deleted: look for the last node before it
+        in = func(n *node[K, V]) bool { return n.key < bound }
+    }
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V], p *V) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, *p, true
+}
+
+func (set *DataSet[K, V]) unlink(key K, node_found *node[K, V]) { // Physical deletion of a node, logically deleted by the caller
+    var preds  [optik_max_level]*node[K, V]
+    var predsv [optik_max_level]optik.Mutex
+    var unused optik.Mutex
+
+restart:
+    set.optik_search(key, preds[:], predsv[:], &unused)
+
+    toplevel_nf := node_found.toplevel
+    var pred_prev *node[K, V] = nil
+    for i := int(0); i < int(toplevel_nf); i++ {
+        pred := preds[i]
+        if pred_prev != pred && !pred.lock.TryLock_version(predsv[i]) {
+            unlock_levels_down(preds[:], 0, i - 1)
+            goto restart
+        }
+        pred_prev = pred
+    }
+
+    for i := uint32(0); i < toplevel_nf; i++ {
+        preds[i].next[i] = node_found.next[i]
+    }
+    unlock_levels_down(preds[:], 0, int(toplevel_nf - 1))
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    var preds  [optik_max_level]*node[K, V]
+    var predsv [optik_max_level]optik.Mutex
+    var node_foundv optik.Mutex
+    var zero V
+
+    for {
+        node_found := set.optik_search(key, preds[:], predsv[:], &node_foundv)
+        if node_found == nil {
+            val, action := fn(zero, false)
+            if action != share.ACTION_STORE {
+                return zero, false
+            }
+            if set.Insert(key, val) {
+                return val, true
+            }
+            continue
+        }
+        if optik.Is_deleted(node_foundv) { // Wait for it to be physically removed
+            runtime.Gosched()
+            continue
+        }
+        p := node_found.ref.Load() // Read after the version, which any replacement changes
+        val, action := fn(*p, true)
+        switch action {
+        case share.ACTION_STORE:
+            if !node_found.lock.TryLock_version(node_foundv) {
+                continue
+            }
+            node_found.ref.Store(&val)
+            node_found.lock.Unlock()
+            return val, true
+        case share.ACTION_DELETE:
+            if node_found.state == 0 || !node_found.lock.TryLock_vdelete(node_foundv) { // Not fully linked yet, or modified meanwhile
+                runtime.Gosched()
+                continue
+            }
+            set.unlink(key, node_found)
+            set.count.Add(-1)
+            return zero, false
+        default:
+            return *p, true
+        }
+    }
+}
+
+/** Try once to collect a range atomically: the predecessor of the range and the nodes in the range on level 0, each version read before the next pointer and the value, then validated against the versions.
+ * @param lo  Lower bound of the range, nil for none
+ * @param hi  Upper bound of the range, nil for none
+ * @param res Buffer to append the pairs of the range to
+ * @return 'res' with the pairs of the range appended, and whether no node was locked nor modified meanwhile
+**/
+func (set *DataSet[K, V]) try_range_atomic(lo *K, hi *K, res []dataset.Pair[K, V]) ([]dataset.Pair[K, V], bool) {
+    var nodes []*node[K, V]
+    var versions []optik.Mutex
+    pred := set.head
+    if lo != nil {
+        pred = set.optik_lower_pred(*lo)
+    }
+    predv := pred.lock.Load()
+    curr := pred.next[0]
+    for lo != nil && curr.less(*lo) {
+        pred = curr
+        predv = pred.lock.Load()
+        curr = pred.next[0]
+    }
+    if optik.Is_locked(predv) { // Also if deleted
+        return res, false
+    }
+    nodes = append(nodes, pred)
+    versions = append(versions, predv)
+    for curr.bound == share.BOUND_NONE && (hi == nil || curr.key <= *hi) {
+        currv := curr.lock.Load()
+        if optik.Is_locked(currv) {
+            return res, false
+        }
+        nodes = append(nodes, curr)
+        versions = append(versions, currv)
+        res = append(res, dataset.Pair[K, V]{Key: curr.key, Val: *curr.ref.Load()})
+        curr = curr.next[0]
+    }
+    // Validate: any insertion in the range locks its predecessor on level 0, any deletion (resp. replacement) in the range deletes (resp. bumps) the node's version
+    for i, node := range nodes {
+        if !optik.Is_same_version(versions[i], node.lock.Load()) {
+            return res, false
+        }
+    }
+    return res, true
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax and AtomicSize
+    level_max := share.Or_default(opts.LevelMax, share.DEFAULT_LEVEL_MAX)
+    if level_max > optik_max_level {
+        return nil, share.Invalid_option("skiplist_optik1", "maximum level", level_max, fmt.Sprintf("at most %v", optik_max_level))
+    }
+    init_rand_level()
+    set := new(DataSet[K, V])
+    set.level_max = level_max
+    max := new_sentinel[K, V](share.BOUND_MAX, nil, level_max)
+    min := new_sentinel(share.BOUND_MIN, max, level_max)
+    set.head = min
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    node := set.head.next[0] // We have at least 2 elements
+    for node.next[0] != nil {
+        if !optik.Is_deleted(node.lock) {
+            size++
+        }
+        node = node.next[0]
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    nd := set.optik_left_search(key)
+    if nd != nil {
+        if p := nd.value(); p != nil {
+            return *p, true
+        }
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var preds  [optik_max_level]*node[K, V]
+    var predsv [optik_max_level]optik.Mutex
+    var unused optik.Mutex
+    var node_new *node[K, V] = nil
+
+    toplevel := int(get_rand_level(set.level_max))
+    inserted_upto := int(0)
+
+restart:
+    node_found := set.optik_search(key, preds[:], predsv[:], &unused)
+    if node_found != nil {
+        if inserted_upto == 0 {
+            if !optik.Is_deleted(node_found.lock) {
+                return false
+            } else { // There is a logically deleted node -- wait for it to be physically removed
+                goto restart
+            }
+        }
+    }
+    if node_new == nil {
+        node_new = new_simple_node(key, val, uint32(toplevel), set.level_max)
+    }
+    var pred_prev *node[K, V] = nil
+    for i := inserted_upto; i < toplevel; i++ {
+        pred := preds[i]
+        if pred_prev != pred && !pred.lock.TryLock_version(predsv[i]) {
+            unlock_levels_down(preds[:], inserted_upto, i - 1)
+            inserted_upto = i
+            goto restart
+        }
+        node_new.next[i] = pred.next[i]
+        pred.next[i] = node_new
+        pred_prev = pred
+    }
+    node_new.state = 1
+    unlock_levels_down(preds[:], inserted_upto, toplevel - 1)
+    set.count.Add(1)
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    var preds  [optik_max_level]*node[K, V]
+    var predsv [optik_max_level]optik.Mutex
+    var node_foundv optik.Mutex
+
+restart:
+    node_found := set.optik_search(key, preds[:], predsv[:], &node_foundv)
+    if node_found == nil {
+        var zero V
+        return zero, false
+    }
+
+    if optik.Is_deleted(node_found.lock) || node_found.state == 0 {
+        var zero V
+        return zero, false
+    }
+    if !node_found.lock.TryLock_vdelete(node_foundv) {
+        if (optik.Is_deleted(node_found.lock)) {
+            var zero V
+            return zero, false
+        } else {
+            goto restart
+        }
+    }
+
+    val := *node_found.ref.Load()
+    set.unlink(key, node_found)
+    set.count.Add(-1)
+    return val, true
+}
+
+func (set *DataSet[K, V]) Snapshot() []dataset.Pair[K, V] { // Collect the whole skip list as 'RangeAtomic' does, only locking it after 'share.SNAPSHOT_RETRIES' failed attempts
+    var res []dataset.Pair[K, V]
+    for i := 0; i < share.SNAPSHOT_RETRIES; i++ {
+        var ok bool
+        if res, ok = set.try_range_atomic(nil, nil, res[:0]); ok {
+            return res
+        }
+        runtime.Gosched()
+    }
+    return set.range_locked(nil, nil, res[:0])
+}
+
+/** Collect a range with its nodes locked, from the predecessor of the range on level 0 (the head if deleted meanwhile) to its end, the deleted nodes being skipped.
+ * @param lo  Lower bound of the range, nil for none
+ * @param hi  Upper bound of the range, nil for none
+ * @param res Buffer to append the pairs of the range to
+ * @return 'res' with the pairs of the range appended
+**/
+func (set *DataSet[K, V]) range_locked(lo *K, hi *K, res []dataset.Pair[K, V]) []dataset.Pair[K, V] {
+    pred := set.head
+    if lo != nil {
+        pred = set.optik_lower_pred(*lo)
+    }
+    for {
+        predv := pred.lock.Load()
+        if optik.Is_deleted(predv) { // Start from the head instead, which never is
+            pred = set.head
+            continue
+        }
+        if pred.lock.TryLock_version(predv) {
+            break
+        }
+        runtime.Gosched()
+    }
+    locked := []*node[K, V]{pred}
+    curr := pred.next[0]
+    for curr.bound == share.BOUND_NONE && (hi == nil || curr.key <= *hi) {
+        currv := curr.lock.Load()
+        if optik.Is_deleted(currv) { // Cannot be unlinked while its predecessor is locked, nor get a new successor
+            curr = curr.next[0]
+            continue
+        }
+        if !curr.lock.TryLock_version(currv) { // Locked by an update, or deleted meanwhile
+            runtime.Gosched()
+            continue
+        }
+        if lo != nil && curr.less(*lo) { // Inserted before the range meanwhile, the new predecessor of the range
+            locked[0].lock.Unlock()
+            locked[0] = curr
+        } else {
+            locked = append(locked, curr)
+            res = append(res, dataset.Pair[K, V]{Key: curr.key, Val: *curr.ref.Load()})
+        }
+        curr = curr.next[0]
+    }
+    for _, node := range locked {
+        node.lock.Unlock()
+    }
+    return res
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: link the nodes in order at each of their levels, see 'share.Bulk_level'
+    if set.Size() != 0 {
+        return share.Invalid_load("skiplist_optik1", "the set is not empty")
+    }
+    if err := share.Check_sorted("skiplist_optik1", pairs); err != nil {
+        return err
+    }
+    var last [optik_max_level]*node[K, V] // Last node linked at each level
+    tail := set.head.next[0]
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl] = set.head
+    }
+    for i, pair := range pairs {
+        toplevel := share.Bulk_level(uint(i + 1), set.level_max)
+        node := new_simple_node(pair.Key, pair.Val, uint32(toplevel), set.level_max)
+        node.state = 1
+        for lvl := uint(0); lvl < toplevel; lvl++ {
+            last[lvl].next[lvl] = node
+            last[lvl] = node
+        }
+    }
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl].next[lvl] = tail
+    }
+    set.count.Add(int64(len(pairs)))
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent, no version being checked
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        scan(set.head.next[0], yield)
+    }
+}
+
+func (set *DataSet[K, V]) RangeAtomic(lo K, hi K, fn func(key K, val V) bool) { // Only locking the range after 'share.SNAPSHOT_RETRIES' failed attempts
+    var pairs []dataset.Pair[K, V]
+    ok := false
+    for i := 0; i < share.SNAPSHOT_RETRIES && !ok; i++ {
+        if pairs, ok = set.try_range_atomic(&lo, &hi, pairs[:0]); !ok {
+            runtime.Gosched()
+        }
+    }
+    if !ok {
+        pairs = set.range_locked(&lo, &hi, pairs[:0])
+    }
+    for _, pair := range pairs {
+        if !fn(pair.Key, pair.Val) {
+            return
+        }
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(set.head.next[0]))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
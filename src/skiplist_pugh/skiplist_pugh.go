@@ -0,0 +1,457 @@
+/**
+ * @file   skiplist_pugh.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * William Pugh.
+ * Concurrent Maintenance of Skip Lists. Technical report, 1990.
+**/
+
+package skiplist_pugh
+
+import (
+    "cmp"
+    "fmt"
+    "iter"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/counter"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+const (
+    maxlevel = uint(32)
+    herlihy_maxlevel = uint(64) // Covers up to 2^64 elements
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    ref atomic.Pointer[V] // Current value, pointing to 'val' until replaced (under the lock)
+    bound share.Bound
+    toplevel uint32
+    lock ttas.Mutex
+    next []*node[K, V]
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    level_max uint
+    head *node[K, V]
+    count *counter.Striped // Amount of elements, nil unless 'AtomicSize'
+}
+
+// -----------------------------------------------------------------------------
+
+var state xorshift.State
+
+func init_rand_level() {
+    state.Init()
+}
+
+func get_rand_level(level_max uint) uint {
+    level := uint(1)
+    for i := uint(0); i < level_max - 1; i++ {
+        if state.Intn(100) < 50 {
+            level++
+        } else {
+            break
+        }
+    }
+    return level
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func (n *node[K, V]) before(other *node[K, V]) bool { // Whether 'n' is strictly before 'other', sentinels included
+    if n.bound != share.BOUND_NONE || other.bound != share.BOUND_NONE {
+        return n.bound < other.bound
+    }
+    return n.key < other.key
+}
+
+func new_simple_node[K cmp.Ordered, V any](key K, val V, toplevel uint32, level_max uint) *node[K, V] {
+    elem := new(node[K, V])
+    elem.key = key
+    elem.val = val
+    elem.ref.Store(&elem.val)
+    elem.toplevel = toplevel
+    elem.next = make([]*node[K, V], level_max)
+    return elem
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V], toplevel uint32, level_max uint) *node[K, V] {
+    node := new_simple_node(key, val, toplevel, level_max)
+    for i := uint(0); i < level_max; i++ {
+        node.next[i] = next
+    }
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V], level_max uint) *node[K, V] {
+    var key K
+    var val V
+    elem := new_node(key, val, next, uint32(level_max), level_max)
+    elem.bound = bound
+    return elem
+}
+
+func get_lock[K cmp.Ordered, V any](pred *node[K, V], key K, lvl uint32) *node[K, V] {
+    succ := pred.next[lvl]
+    for succ.less(key) {
+        pred = succ
+        succ = succ.next[lvl]
+    }
+
+    pred.lock.Lock()
+    succ = pred.next[lvl]
+    for succ.less(key) {
+        pred.lock.Unlock()
+        pred = succ
+        pred.lock.Lock()
+        succ = pred.next[lvl]
+    }
+
+    return pred
+}
+
+func scan[K cmp.Ordered, V any](elem *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the nodes of level 0 from 'elem' to the tail, until it returns false
+    var last K
+    seen := false
+    for elem.bound != share.BOUND_MAX {
+        if elem.bound == share.BOUND_NONE && (!seen || last < elem.key) { // A deleted node points back to its predecessor: skip what was already visited
+            if !fn(elem.key, *elem.ref.Load()) {
+                return
+            }
+            last = elem.key
+            seen = true
+        }
+        elem = elem.next[0]
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of level 0 of key not less than 'key' (greater if 'strict'), deleted or not
+    pred := set.head
+    var curr *node[K, V]
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        curr = pred.next[i]
+        for curr.less(key) || (strict && curr.equal(key)) {
+            pred = curr
+            curr = pred.next[i]
+        }
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) *node[K, V] { // 'curr', nil if the tail
+    if curr.bound == share.BOUND_MAX {
+        return nil
+    }
+    return curr
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) *node[K, V] { // Last node among the first nodes satisfying 'in', nil if none
+    pred := set.head
+    for i := int(set.level_max - 1); i >= 0; i-- {
+        curr := pred.next[i]
+        for curr.bound == share.BOUND_NONE && in(curr) {
+            pred = curr
+            curr = pred.next[i]
+        }
+    }
+    if pred.bound != share.BOUND_NONE {
+        return nil
+    }
+    return pred
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V]) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, *n.ref.Load(), true
+}
+
+func (set *DataSet[K, V]) lock_node(key K, update []*node[K, V]) *node[K, V] { // Lock the node of the given key, filling the predecessors at each level; nil if absent
+    var succ *node[K, V]
+    pred := set.head
+    for lvl := int(set.level_max - 1); lvl >= 0; lvl-- {
+        succ = pred.next[lvl]
+        for succ.less(key) {
+            pred = succ
+            succ = succ.next[lvl]
+        }
+        update[lvl] = pred
+    }
+
+    succ = pred
+    for {
+        succ = succ.next[0]
+        if !succ.less(key) && !succ.equal(key) {
+            return nil
+        }
+        succ.lock.Lock()
+        if !succ.next[0].before(succ) && succ.equal(key) {
+            return succ
+        }
+        succ.lock.Unlock()
+    }
+}
+
+func (set *DataSet[K, V]) unlink(key K, update []*node[K, V], succ *node[K, V]) { // Physical deletion of a node, locked by 'lock_node' (unlocked on return)
+    for lvl := int(succ.toplevel - 1); lvl >= 0; lvl-- {
+        pred := get_lock(update[lvl], key, uint32(lvl))
+        pred.next[lvl] = succ.next[lvl]
+        succ.next[lvl] = pred
+        pred.lock.Unlock()
+    }
+    succ.lock.Unlock()
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Atomic update of a key, see 'share.Update'
+    var update [herlihy_maxlevel]*node[K, V]
+    var zero V
+    for {
+        succ := set.lock_node(key, update[:])
+        if succ == nil {
+            val, action := fn(zero, false)
+            if action != share.ACTION_STORE {
+                return zero, false
+            }
+            if set.Insert(key, val) {
+                return val, true
+            }
+            continue
+        }
+        p := succ.ref.Load()
+        val, action := fn(*p, true)
+        switch action {
+        case share.ACTION_STORE:
+            succ.ref.Store(&val)
+            succ.lock.Unlock()
+            return val, true
+        case share.ACTION_DELETE:
+            set.unlink(key, update[:], succ)
+            set.count.Add(-1)
+            return zero, false
+        default:
+            succ.lock.Unlock()
+            return *p, true
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax and AtomicSize
+    level_max := share.Or_default(opts.LevelMax, share.DEFAULT_LEVEL_MAX)
+    if level_max > maxlevel {
+        return nil, share.Invalid_option("skiplist_pugh", "maximum level", level_max, fmt.Sprintf("at most %v", maxlevel))
+    }
+    init_rand_level()
+    set := new(DataSet[K, V])
+    set.level_max = level_max
+    max := new_sentinel[K, V](share.BOUND_MAX, nil, level_max)
+    min := new_sentinel(share.BOUND_MIN, max, level_max)
+    set.head = min
+    if opts.AtomicSize {
+        set.count = counter.New()
+    }
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    if set.count != nil {
+        return set.count.Size()
+    }
+    var size uint = 0
+    node := set.head.next[0] // We have at least 2 elements
+    for (node.next[0] != nil) {
+        size++
+        node = node.next[0]
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    pred := set.head
+    for lvl := int(set.level_max - 1); lvl >= 0; lvl-- {
+        succ := pred.next[lvl]
+        for (succ.less(key)) {
+            pred = succ
+            succ = succ.next[lvl]
+        }
+        if (succ.equal(key)) {
+            return *succ.ref.Load(), true
+        }
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var update [herlihy_maxlevel]*node[K, V]
+    pred := set.head
+    for lvl := int(set.level_max - 1); lvl >= 0; lvl-- {
+        succ := pred.next[lvl]
+        for succ.less(key) {
+            pred = succ
+            succ = succ.next[lvl]
+        }
+        if succ.equal(key) {
+            return false
+        }
+        update[lvl] = pred
+    }
+
+    rand_lvl := get_rand_level(set.level_max)
+
+    pred = get_lock(pred, key, 0)
+    if pred.next[0].equal(key) {
+        pred.lock.Unlock()
+        return false
+    }
+
+    n := new_simple_node(key, val, uint32(rand_lvl), set.level_max)
+    n.lock.Lock()
+    n.next[0] = pred.next[0] // We already hold the lock for lvl 0
+    /// TODO: Ensure no reordoring here
+    pred.next[0] = n
+    pred.lock.Unlock()
+    for lvl := uint32(1); lvl < n.toplevel; lvl++ {
+        pred = get_lock(update[lvl], key, lvl)
+        n.next[lvl] = pred.next[lvl]
+        /// TODO: Ensure no reordoring here
+        pred.next[lvl] = n
+        pred.lock.Unlock()
+    }
+    n.lock.Unlock()
+    set.count.Add(1)
+    return true
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    var update [herlihy_maxlevel]*node[K, V]
+    succ := set.lock_node(key, update[:])
+    if succ == nil {
+        var zero V
+        return zero, false
+    }
+    val := *succ.ref.Load()
+    set.unlink(key, update[:], succ)
+    set.count.Add(-1)
+    return val, true
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: link the nodes in order at each of their levels, see 'share.Bulk_level'
+    if set.Size() != 0 {
+        return share.Invalid_load("skiplist_pugh", "the set is not empty")
+    }
+    if err := share.Check_sorted("skiplist_pugh", pairs); err != nil {
+        return err
+    }
+    var last [herlihy_maxlevel]*node[K, V] // Last node linked at each level
+    tail := set.head.next[0]
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl] = set.head
+    }
+    for i, pair := range pairs {
+        toplevel := share.Bulk_level(uint(i + 1), set.level_max)
+        node := new_simple_node(pair.Key, pair.Val, uint32(toplevel), set.level_max)
+        for lvl := uint(0); lvl < toplevel; lvl++ {
+            last[lvl].next[lvl] = node
+            last[lvl] = node
+        }
+    }
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl].next[lvl] = tail
+    }
+    set.count.Add(int64(len(pairs)))
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Weakly consistent and lock-free, keys being visited in strictly increasing order
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        scan(set.head.next[0], yield)
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(set.head.next[0]))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
@@ -0,0 +1,381 @@
+/**
+ * @file   skiplist_seq.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2014 Vasileios Trigonakis <vasileios.trigonakis@epfl.ch>,
+ *                    Tudor David <tudor.david@epfl.ch>
+ *                    Distributed Programming Lab (LPD), EPFL
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * A sequential skiplist (= doesn't support concurrency).
+**/
+
+package skiplist_seq
+
+import (
+    "cmp"
+    "fmt"
+    "iter"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+const (
+    maxlevel = uint(32)
+)
+
+// -----------------------------------------------------------------------------
+
+type node[K cmp.Ordered, V any] struct {
+    key K
+    val V
+    bound share.Bound
+    toplevel uint32
+    next []*node[K, V]
+}
+
+type DataSet[K cmp.Ordered, V any] struct {
+    level_max uint
+    head *node[K, V]
+}
+
+// -----------------------------------------------------------------------------
+
+var state xorshift.State
+
+func init_rand_level() {
+    state.Init()
+}
+
+func get_rand_level(level_max uint) uint {
+    level := uint(1)
+    for i := uint(0); i < level_max - 1; i++ {
+        if state.Intn(100) < 50 {
+            level++
+        } else {
+            break
+        }
+    }
+    return level
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *node[K, V]) less(key K) bool {
+    return n.bound == share.BOUND_MIN || (n.bound == share.BOUND_NONE && n.key < key)
+}
+
+func (n *node[K, V]) equal(key K) bool {
+    return n.bound == share.BOUND_NONE && n.key == key
+}
+
+func new_simple_node[K cmp.Ordered, V any](key K, val V, toplevel uint32, level_max uint) *node[K, V] {
+    elem := new(node[K, V])
+    elem.key = key
+    elem.val = val
+    elem.toplevel = toplevel
+    elem.next = make([]*node[K, V], level_max)
+    return elem
+}
+
+func new_node[K cmp.Ordered, V any](key K, val V, next *node[K, V], toplevel uint32, level_max uint) *node[K, V] {
+    node := new_simple_node(key, val, toplevel, level_max)
+    for i := uint(0); i < level_max; i++ {
+        node.next[i] = next
+    }
+    return node
+}
+
+func new_sentinel[K cmp.Ordered, V any](bound share.Bound, next *node[K, V], level_max uint) *node[K, V] {
+    var key K
+    var val V
+    elem := new_node(key, val, next, uint32(level_max), level_max)
+    elem.bound = bound
+    return elem
+}
+
+func is_marked[K cmp.Ordered, V any](i *node[K, V]) bool {
+    return (uintptr(unsafe.Pointer(i)) & 1) != 0
+}
+
+func unset_mark[K cmp.Ordered, V any](i *node[K, V]) *node[K, V] {
+    if !is_marked(i) {
+        return i
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(i), -1)) // Stay in unsafe.Add, so the GC keeps seeing a pointer
+}
+
+func set_mark[K cmp.Ordered, V any](i *node[K, V]) *node[K, V] {
+    if is_marked(i) {
+        return i
+    }
+    return (*node[K, V])(unsafe.Add(unsafe.Pointer(i), 1))
+}
+
+func scan[K cmp.Ordered, V any](node *node[K, V], fn func(key K, val V) bool) { // Call 'fn' on the nodes of level 0 from 'node' to the tail, until it returns false
+    for node.bound != share.BOUND_MAX {
+        if !fn(node.key, node.val) {
+            return
+        }
+        node = node.next[0]
+    }
+}
+
+func (set *DataSet[K, V]) search(key K, strict bool) *node[K, V] { // First node of level 0 of key not less than 'key' (greater if 'strict'), deleted or not
+    pred := set.head
+    var curr *node[K, V]
+    for i := int(set.head.toplevel - 1); i >= 0; i-- {
+        curr = pred.next[i]
+        for curr.less(key) || (strict && curr.equal(key)) {
+            pred = curr
+            curr = pred.next[i]
+        }
+    }
+    return curr
+}
+
+func first[K cmp.Ordered, V any](curr *node[K, V]) *node[K, V] { // 'curr', nil if the tail
+    if curr.bound == share.BOUND_MAX {
+        return nil
+    }
+    return curr
+}
+
+func (set *DataSet[K, V]) last(in func(n *node[K, V]) bool) *node[K, V] { // Last node among the first nodes satisfying 'in', nil if none
+    pred := set.head
+    for i := int(set.head.toplevel - 1); i >= 0; i-- {
+        curr := pred.next[i]
+        for curr.bound == share.BOUND_NONE && in(curr) {
+            pred = curr
+            curr = pred.next[i]
+        }
+    }
+    if pred.bound != share.BOUND_NONE {
+        return nil
+    }
+    return pred
+}
+
+func unpack[K cmp.Ordered, V any](n *node[K, V]) (K, V, bool) {
+    if n == nil {
+        var key K
+        var val V
+        return key, val, false
+    }
+    return n.key, n.val, true
+}
+
+func (set *DataSet[K, V]) update(key K, fn func(val V, ok bool) (V, share.Action)) (V, bool) { // Update of a key, see 'share.Update' (not thread-safe)
+    node := set.search(key, false)
+    found := node.equal(key)
+    var cur V
+    if found {
+        cur = node.val
+    }
+    val, action := fn(cur, found)
+    switch {
+    case action == share.ACTION_STORE && found:
+        node.val = val
+    case action == share.ACTION_STORE:
+        set.Insert(key, val)
+    case action == share.ACTION_DELETE:
+        set.Delete(key)
+        var zero V
+        return zero, false
+    default:
+        return cur, found
+    }
+    return val, true
+}
+
+// -----------------------------------------------------------------------------
+
+func New[K cmp.Ordered, V any](opts share.Options) (*DataSet[K, V], error) { // Uses LevelMax
+    level_max := share.Or_default(opts.LevelMax, share.DEFAULT_LEVEL_MAX)
+    if level_max > maxlevel {
+        return nil, share.Invalid_option("skiplist_seq", "maximum level", level_max, fmt.Sprintf("at most %v", maxlevel))
+    }
+    init_rand_level()
+    set := new(DataSet[K, V])
+    set.level_max = level_max
+    max := new_sentinel[K, V](share.BOUND_MAX, nil, level_max)
+    min := new_sentinel(share.BOUND_MIN, max, level_max)
+    set.head = min
+    return set, nil
+}
+
+func (set *DataSet[K, V]) Destroy() {
+}
+
+func (set *DataSet[K, V]) Size() uint {
+    var size uint = 0
+    node := unset_mark(set.head.next[0])
+    for node.next[0] != nil {
+        if (!is_marked(node.next[0])) {
+            size++
+        }
+        node = unset_mark(node.next[0])
+    }
+    return size
+}
+
+func (set *DataSet[K, V]) Find(key K) (V, bool) {
+    var node, next *node[K, V] = set.head, nil
+    for i := int(node.toplevel - 1); i >= 0; i-- {
+        next = node.next[i]
+        for next.less(key) {
+            node = next
+            next = node.next[i]
+        }
+    }
+    node = node.next[0]
+    if node.equal(key) {
+        return node.val, true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) Insert(key K, val V) bool {
+    var preds, succs [maxlevel]*node[K, V]
+    var node, next *node[K, V] = set.head, nil
+    for i := int(node.toplevel - 1); i >= 0; i-- {
+        next = node.next[i]
+        for next.less(key) {
+            node = next
+            next = node.next[i]
+        }
+        preds[i] = node
+        succs[i] = node.next[i]
+    }
+    node = node.next[0]
+    if !node.equal(key) {
+        l := get_rand_level(set.level_max)
+        node = new_simple_node(key, val, uint32(l), set.level_max)
+        for i := uint(0); i < l; i++ {
+            node.next[i] = succs[i]
+            preds[i].next[i] = node
+        }
+        return true
+    }
+    return false
+}
+
+func (set *DataSet[K, V]) Delete(key K) (V, bool) {
+    var preds, succs [maxlevel]*node[K, V]
+    var node, next *node[K, V] = set.head, nil
+    for i := int(node.toplevel - 1); i >= 0; i-- {
+        next = node.next[i]
+        for next.less(key) {
+            node = next
+            next = node.next[i]
+        }
+        preds[i] = node
+        succs[i] = node.next[i]
+    }
+    if next.equal(key) {
+        result := next.val
+        for i := uint32(0); i < set.head.toplevel; i++ {
+            if succs[i].equal(key) {
+                preds[i].next[i] = succs[i].next[i]
+            }
+        }
+        return result, true
+    }
+    var zero V
+    return zero, false
+}
+
+func (set *DataSet[K, V]) BulkLoad(pairs []dataset.Pair[K, V]) error { // Not thread-safe: link the nodes in order at each of their levels, see 'share.Bulk_level'
+    if set.Size() != 0 {
+        return share.Invalid_load("skiplist_seq", "the set is not empty")
+    }
+    if err := share.Check_sorted("skiplist_seq", pairs); err != nil {
+        return err
+    }
+    var last [maxlevel]*node[K, V] // Last node linked at each level
+    tail := set.head.next[0]
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl] = set.head
+    }
+    for i, pair := range pairs {
+        toplevel := share.Bulk_level(uint(i + 1), set.level_max)
+        node := new_simple_node(pair.Key, pair.Val, uint32(toplevel), set.level_max)
+        for lvl := uint(0); lvl < toplevel; lvl++ {
+            last[lvl].next[lvl] = node
+            last[lvl] = node
+        }
+    }
+    for lvl := uint(0); lvl < set.level_max; lvl++ {
+        last[lvl].next[lvl] = tail
+    }
+    return nil
+}
+
+func (set *DataSet[K, V]) Put(key K, val V) (V, bool) {
+    return share.Put(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) CompareAndSwap(key K, old V, new V) bool {
+    return share.CompareAndSwap(set.update, key, old, new)
+}
+
+func (set *DataSet[K, V]) LoadOrStore(key K, val V) (V, bool) {
+    return share.LoadOrStore(set.update, key, val)
+}
+
+func (set *DataSet[K, V]) Compute(key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return share.Compute(set.update, key, fn)
+}
+
+func (set *DataSet[K, V]) Range(lo K, hi K, fn func(key K, val V) bool) { // Not thread-safe, like every other operation
+    scan(set.search(lo, false), func(key K, val V) bool {
+        return key <= hi && fn(key, val)
+    })
+}
+
+func (set *DataSet[K, V]) All() iter.Seq2[K, V] {
+    return func(yield func(key K, val V) bool) {
+        scan(set.head.next[0], yield)
+    }
+}
+
+func (set *DataSet[K, V]) Min() (K, V, bool) {
+    return unpack(first(set.head.next[0]))
+}
+
+func (set *DataSet[K, V]) Max() (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return true }))
+}
+
+func (set *DataSet[K, V]) Ceiling(key K) (K, V, bool) {
+    return unpack(first(set.search(key, false)))
+}
+
+func (set *DataSet[K, V]) Floor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key <= key }))
+}
+
+func (set *DataSet[K, V]) Successor(key K) (K, V, bool) {
+    return unpack(first(set.search(key, true)))
+}
+
+func (set *DataSet[K, V]) Predecessor(key K) (K, V, bool) {
+    return unpack(set.last(func(n *node[K, V]) bool { return n.key < key }))
+}
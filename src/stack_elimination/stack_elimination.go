@@ -0,0 +1,170 @@
+/**
+ * @file   stack_elimination.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Hendler, Shavit and Yerushalmi's elimination-backoff stack: a Treiber stack
+ * whose operations back off, after a failed CAS on the top, in a collision
+ * array instead of just yielding.
+ * An operation waiting in a slot of the array is cancelled out by the first
+ * opposite operation colliding with it: a push hands its value to a pop, and
+ * neither of them touches the stack. An operation colliding with a same one,
+ * or waiting in vain, retries on the stack.
+**/
+
+package stack_elimination
+
+import (
+    "math/rand/v2"
+    "runtime"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+)
+
+const (
+    wait_spins = 64 // Amount of checks of an offer before withdrawing it
+)
+
+// -----------------------------------------------------------------------------
+
+type node[V any] struct {
+    val V
+    next *node[V]
+}
+
+type offer[V any] struct {
+    push bool
+    val V              // Value pushed, or received by the pop once 'done'
+    done atomic.Bool   // Set by the colliding operation
+}
+
+type DataSet[V any] struct {
+    top atomic.Pointer[node[V]]
+    slots []atomic.Pointer[offer[V]] // Collision array, a slot holding the waiting offer if any
+}
+
+// -----------------------------------------------------------------------------
+
+func new_node[V any](val V, next *node[V]) *node[V] {
+    elem := new(node[V])
+    elem.val = val
+    elem.next = next
+    return elem
+}
+
+/** Try to cancel an operation out with an opposite one, in a random slot of the collision array.
+ * @param push Whether the operation is a push
+ * @param val  Value pushed (ignored for a pop)
+ * @return Value popped (for a pop), and whether the operation was cancelled out
+**/
+func (set *DataSet[V]) collide(push bool, val V) (V, bool) {
+    var zero V
+    slot := &set.slots[rand.IntN(len(set.slots))]
+    if other := slot.Load(); other != nil { // Collision with a waiting operation
+        if other.push == push || !slot.CompareAndSwap(other, nil) {
+            return zero, false
+        }
+        if push {
+            other.val = val
+            other.done.Store(true)
+            return zero, true
+        }
+        res := other.val
+        other.done.Store(true)
+        return res, true
+    }
+    mine := new(offer[V])
+    mine.push = push
+    mine.val = val
+    if !slot.CompareAndSwap(nil, mine) {
+        return zero, false
+    }
+    for i := 0; i < wait_spins; i++ {
+        if mine.done.Load() {
+            return mine.val, true
+        }
+        runtime.Gosched()
+    }
+    if slot.CompareAndSwap(mine, nil) { // Withdrawn
+        return zero, false
+    }
+    for !mine.done.Load() { // Taken by an opposite operation, which completes it right away
+        runtime.Gosched()
+    }
+    return mine.val, true
+}
+
+// -----------------------------------------------------------------------------
+
+func New[V any](opts share.Options) (*DataSet[V], error) { // No option used, one slot per two processors
+    set := new(DataSet[V])
+    set.slots = make([]atomic.Pointer[offer[V]], max(runtime.GOMAXPROCS(0) / 2, 1))
+    return set, nil
+}
+
+func (set *DataSet[V]) Destroy() {
+}
+
+func (set *DataSet[V]) Size() uint {
+    size := uint(0)
+    for node := set.top.Load(); node != nil; node = node.next {
+        size++
+    }
+    return size
+}
+
+func (set *DataSet[V]) Push(val V) {
+    elem := new_node(val, nil)
+    for {
+        top := set.top.Load()
+        elem.next = top
+        if set.top.CompareAndSwap(top, elem) {
+            return
+        }
+        if _, ok := set.collide(true, val); ok {
+            return
+        }
+    }
+}
+
+func (set *DataSet[V]) Pop() (V, bool) {
+    for {
+        top := set.top.Load()
+        if top == nil {
+            var zero V
+            return zero, false
+        }
+        if set.top.CompareAndSwap(top, top.next) {
+            return top.val, true
+        }
+        var zero V
+        if val, ok := set.collide(false, zero); ok {
+            return val, true
+        }
+    }
+}
+
+func (set *DataSet[V]) Peek() (V, bool) {
+    top := set.top.Load()
+    if top == nil {
+        var zero V
+        return zero, false
+    }
+    return top.val, true
+}
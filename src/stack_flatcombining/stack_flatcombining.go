@@ -0,0 +1,198 @@
+/**
+ * @file   stack_flatcombining.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Hendler, Incze, Shavit and Tzafrir's flat-combining stack: each operation
+ * publishes a request in a slot of a publication array, then either waits for
+ * it to be served, or takes the (test-and-test-and-set) lock and becomes the
+ * combiner, serving every published request on a sequential stack (an
+ * operation finding the lock free serves itself first, without publishing).
+ * The combiner first pairs the pushes with the pops it collected, each pop
+ * receiving the value of a push without touching the stack.
+ * Goroutines having no identity, a request takes any free slot, from a random
+ * one, instead of a per-thread record.
+**/
+
+package stack_flatcombining
+
+import (
+    "math/rand/v2"
+    "runtime"
+    "sync/atomic"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/ttas"
+)
+
+const (
+    min_slots = 64  // Minimum amount of slots of the publication array
+    wait_spins = 64 // Amount of checks of a request before trying to become the combiner again
+)
+
+// -----------------------------------------------------------------------------
+
+type request[V any] struct {
+    push bool
+    val V            // Value pushed, or popped once 'done'
+    ok bool          // Whether a value was popped, once 'done'
+    done atomic.Bool // Set by the combiner
+}
+
+type DataSet[V any] struct {
+    lock ttas.Mutex
+    stack []V                          // Sequential stack, only accessed with 'lock' held
+    slots []atomic.Pointer[request[V]] // Publication array, a slot holding a pending request if any
+    pending atomic.Int64               // Amount of published requests, so that the combiner only scans the array if needed
+    pushes []*request[V]               // Pushes collected by the combiner
+    pops []*request[V]                 // Pops collected by the combiner
+}
+
+// -----------------------------------------------------------------------------
+
+func (set *DataSet[V]) combine() { // Serve every published request, with 'lock' held
+    if set.pending.Load() == 0 { // A request being published is served by its own operation, which tries the lock once published
+        return
+    }
+    set.pushes = set.pushes[:0]
+    set.pops = set.pops[:0]
+    for i := range set.slots {
+        req := set.slots[i].Load()
+        if req == nil {
+            continue
+        }
+        set.slots[i].Store(nil) // Only the combiner clears a slot
+        if req.push {
+            set.pushes = append(set.pushes, req)
+        } else {
+            set.pops = append(set.pops, req)
+        }
+    }
+    set.pending.Add(-int64(len(set.pushes) + len(set.pops)))
+    pairs := min(len(set.pushes), len(set.pops))
+    for i := 0; i < pairs; i++ { // Elimination: each pop takes the value of a push
+        push, pop := set.pushes[i], set.pops[i]
+        pop.val, pop.ok = push.val, true
+        push.done.Store(true)
+        pop.done.Store(true)
+    }
+    for _, push := range set.pushes[pairs:] {
+        set.stack = append(set.stack, push.val)
+        push.done.Store(true)
+    }
+    for _, pop := range set.pops[pairs:] {
+        if n := len(set.stack); n > 0 {
+            pop.val, pop.ok = set.stack[n - 1], true
+            var zero V
+            set.stack[n - 1] = zero // Release the value
+            set.stack = set.stack[:n - 1]
+        }
+        pop.done.Store(true)
+    }
+    clear(set.pushes) // Release the requests
+    clear(set.pops)
+}
+
+func (set *DataSet[V]) apply(req *request[V]) { // Apply a request directly, with 'lock' held
+    if req.push {
+        set.stack = append(set.stack, req.val)
+    } else if n := len(set.stack); n > 0 {
+        req.val, req.ok = set.stack[n - 1], true
+        var zero V
+        set.stack[n - 1] = zero
+        set.stack = set.stack[:n - 1]
+    }
+}
+
+func (set *DataSet[V]) execute(req *request[V]) { // Publish the request, and wait until it is served
+    if set.lock.TryLock() { // No combiner, serve 'req' first
+        set.apply(req)
+        set.combine()
+        set.lock.Unlock()
+        return
+    }
+    published := false
+    start := rand.IntN(len(set.slots))
+    for i := range set.slots {
+        if set.slots[(start + i) % len(set.slots)].CompareAndSwap(nil, req) {
+            set.pending.Add(1)
+            published = true
+            break
+        }
+    }
+    if !published { // Every slot is taken, wait for the lock instead
+        set.lock.Lock()
+        set.apply(req)
+        set.combine()
+        set.lock.Unlock()
+        return
+    }
+    for {
+        if set.lock.TryLock() {
+            set.combine() // Serves 'req' as well, unless already done
+            set.lock.Unlock()
+            return
+        }
+        for i := 0; i < wait_spins; i++ { // Another combiner is likely to serve 'req'
+            if req.done.Load() {
+                return
+            }
+            runtime.Gosched()
+        }
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func New[V any](opts share.Options) (*DataSet[V], error) { // No option used, two slots per processor (at least 'min_slots')
+    set := new(DataSet[V])
+    set.slots = make([]atomic.Pointer[request[V]], max(2 * runtime.GOMAXPROCS(0), min_slots))
+    return set, nil
+}
+
+func (set *DataSet[V]) Destroy() {
+}
+
+func (set *DataSet[V]) Size() uint {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    return uint(len(set.stack))
+}
+
+func (set *DataSet[V]) Push(val V) {
+    req := new(request[V])
+    req.push = true
+    req.val = val
+    set.execute(req)
+}
+
+func (set *DataSet[V]) Pop() (V, bool) {
+    req := new(request[V])
+    set.execute(req)
+    return req.val, req.ok
+}
+
+func (set *DataSet[V]) Peek() (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    if n := len(set.stack); n > 0 {
+        return set.stack[n - 1], true
+    }
+    var zero V
+    return zero, false
+}
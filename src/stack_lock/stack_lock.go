@@ -23,35 +23,30 @@
  * A very simple stack implementation (lock-based).
 **/
 
-package dataset
+package stack_lock
 
 import (
     "sync"
-    "tools/share"
-)
 
-const (
-    FindIsDef bool = false
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
 )
 
 // -----------------------------------------------------------------------------
 
-type node struct {
-  key share.Key
-  val share.Val
-  next *node
+type node[V any] struct {
+  val V
+  next *node[V]
 }
 
-type DataSet struct {
-    top *node
+type DataSet[V any] struct {
+    top *node[V]
     lock sync.Mutex
 }
 
 // -----------------------------------------------------------------------------
 
-func new_node(key share.Key, val share.Val, next *node) *node {
-    elem := new(node)
-    elem.key = key
+func new_node[V any](val V, next *node[V]) *node[V] {
+    elem := new(node[V])
     elem.val = val
     elem.next = next
     return elem
@@ -59,14 +54,14 @@ func new_node(key share.Key, val share.Val, next *node) *node {
 
 // -----------------------------------------------------------------------------
 
-func New() *DataSet {
-    return new(DataSet) // 0 initialized by default
+func New[V any](opts share.Options) (*DataSet[V], error) { // No option used
+    return new(DataSet[V]), nil // 0 initialized by default
 }
 
-func (set *DataSet) Destroy() {
+func (set *DataSet[V]) Destroy() {
 }
 
-func (set *DataSet) Size() uint {
+func (set *DataSet[V]) Size() uint {
     size := uint(0)
     node := set.top
     for node != nil {
@@ -76,26 +71,33 @@ func (set *DataSet) Size() uint {
     return size
 }
 
-func (set *DataSet) Find(key share.Key) (share.Val, bool) {
-    return 0, true // Not supposed to use Find with a stack...
-}
-
-func (set *DataSet) Insert(key share.Key, val share.Val) bool {
-    elem := new_node(key, val, nil)
+func (set *DataSet[V]) Push(val V) {
+    elem := new_node(val, nil)
     set.lock.Lock()
     defer set.lock.Unlock()
     elem.next = set.top
     set.top = elem
-    return true
 }
 
-func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
+func (set *DataSet[V]) Pop() (V, bool) {
     set.lock.Lock()
     defer set.lock.Unlock()
     top := set.top
     if top == nil {
-        return 0, false
+        var zero V
+        return zero, false
     }
     set.top = top.next
     return top.val, true
 }
+
+func (set *DataSet[V]) Peek() (V, bool) {
+    set.lock.Lock()
+    defer set.lock.Unlock()
+    top := set.top
+    if top == nil {
+        var zero V
+        return zero, false
+    }
+    return top.val, true
+}
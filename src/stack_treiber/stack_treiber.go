@@ -23,37 +23,32 @@
  * Treiber's concurrent stack.
 **/
 
-package dataset
+package stack_treiber
 
 import (
     "runtime"
     "sync/atomic"
-    "tools/share"
-    "tools/volatile"
     "unsafe"
-)
 
-const (
-    FindIsDef bool = false
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
 )
 
 // -----------------------------------------------------------------------------
 
-type node struct {
-    key share.Key
-    val share.Val
-    next *node
+type node[V any] struct {
+    val V
+    next *node[V]
 }
 
-type DataSet struct {
-    top *node
+type DataSet[V any] struct {
+    top *node[V]
 }
 
 // -----------------------------------------------------------------------------
 
-func new_node(key share.Key, val share.Val, next *node) *node {
-    elem := new(node)
-    elem.key = key
+func new_node[V any](val V, next *node[V]) *node[V] {
+    elem := new(node[V])
     elem.val = val
     elem.next = next
     return elem
@@ -61,14 +56,14 @@ func new_node(key share.Key, val share.Val, next *node) *node {
 
 // -----------------------------------------------------------------------------
 
-func New() *DataSet {
-    return new(DataSet)
+func New[V any](opts share.Options) (*DataSet[V], error) { // No option used
+    return new(DataSet[V]), nil
 }
 
-func (set *DataSet) Destroy() {
+func (set *DataSet[V]) Destroy() {
 }
 
-func (set *DataSet) Size() uint {
+func (set *DataSet[V]) Size() uint {
     size := uint(0)
     node := set.top
     for node != nil {
@@ -78,27 +73,24 @@ func (set *DataSet) Size() uint {
     return size
 }
 
-func (set *DataSet) Find(key share.Key) (share.Val, bool) {
-    return 0, true // Not supposed to use Find with a stack...
-}
-
-func (set *DataSet) Insert(key share.Key, val share.Val) bool {
-    elem := new_node(key, val, nil)
+func (set *DataSet[V]) Push(val V) {
+    elem := new_node(val, nil)
     for {
-        top := (*node)(volatile.ReadPointer((*unsafe.Pointer)(unsafe.Pointer(&set.top))))
+        top := (*node[V])(volatile.ReadPointer((*unsafe.Pointer)(unsafe.Pointer(&set.top))))
         elem.next = top
         if atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&set.top)), unsafe.Pointer(top), unsafe.Pointer(elem)) {
-            return true
+            return
         }
         runtime.Gosched()
     }
 }
 
-func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
+func (set *DataSet[V]) Pop() (V, bool) {
     for {
-        top := (*node)(volatile.ReadPointer((*unsafe.Pointer)(unsafe.Pointer(&set.top))))
+        top := (*node[V])(volatile.ReadPointer((*unsafe.Pointer)(unsafe.Pointer(&set.top))))
         if top == nil {
-            return 0, false
+            var zero V
+            return zero, false
         }
         if atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&set.top)), unsafe.Pointer(top), unsafe.Pointer(top.next)) {
             return top.val, true
@@ -106,3 +98,12 @@ func (set *DataSet) Delete(key share.Key) (share.Val, bool) {
         runtime.Gosched()
     }
 }
+
+func (set *DataSet[V]) Peek() (V, bool) {
+    top := (*node[V])(volatile.ReadPointer((*unsafe.Pointer)(unsafe.Pointer(&set.top))))
+    if top == nil {
+        var zero V
+        return zero, false
+    }
+    return top.val, true
+}
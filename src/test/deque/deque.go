@@ -0,0 +1,271 @@
+/**
+ * @file   deque.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Work-stealing deque test module: one owner thread pushes and pops at the
+ * bottom, while the other threads steal at the top.
+ * Every value pushed is a distinct sequence number, so that the test can check
+ * that no value is lost or duplicated, and that each thief steals values by
+ * increasing sequence number (the top of the deque holding the oldest ones).
+**/
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/registry"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/assert"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/thread"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+// -----------------------------------------------------------------------------
+
+// True if the tests are running
+var running int32
+
+// Test parameters
+type params_t struct {
+    duration uint
+    initial uint
+    num_thieves uint
+    push uint
+    capacity uint
+}
+
+// Thread run statistics
+type stats_t struct {
+    push_count uint64
+    push_sum uint64
+    pop_count uint64
+    pop_count_succ uint64
+    pop_sum uint64
+    steal_count uint64
+    steal_count_succ uint64
+    steal_sum uint64
+    order_errors uint64
+}
+
+// -----------------------------------------------------------------------------
+
+func main() {
+    var params params_t
+    var names string
+    var list bool
+
+    { // Parameters
+        flag.StringVar(&names, "a", "deque_chaselev", "Comma-separated list of deques to test, or 'all'")
+        flag.BoolVar(&list, "list", false, "List the available deques and exit")
+        flag.UintVar(&params.duration, "d", 1000, "Test duration in milliseconds")
+        flag.UintVar(&params.initial, "i", 1024, "Number of elements to push before test")
+        flag.UintVar(&params.num_thieves, "n", 1, "Number of thief threads (besides the owner)")
+        flag.UintVar(&params.push, "p", 75, "Percentage of push operations of the owner (the others being pops)")
+        flag.UintVar(&params.capacity, "c", 0, "Initial capacity of the deque, power of 2 (0 for the default)")
+        flag.Parse()
+
+        if list {
+            for _, entry := range registry.Entries() {
+                if entry.Kind == dataset.KIND_DEQUE {
+                    fmt.Printf("%-30s %v\n", entry.Name, entry.Kind)
+                }
+            }
+            return
+        }
+
+        assert.Assert(params.push <= 100, "The push rate should not be greater than 100 (it is a percentage)")
+        fmt.Printf("## Initial: %v / Push: %v%% / Thieves: %v\n", params.initial, params.push, params.num_thieves)
+    }
+
+    entries, err := registry.Select(names)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    for _, entry := range entries {
+        if entry.Kind != dataset.KIND_DEQUE {
+            assert.Assert(names == "all", "'" + entry.Name + "' is not a deque")
+            continue
+        }
+        run(entry, params)
+    }
+}
+
+/** Run the test on one deque.
+ * @param entry  Deque to test
+ * @param params Test parameters
+**/
+func run(entry registry.Entry, params params_t) {
+    fmt.Printf("### Algorithm: %v (%v)\n", entry.Name, entry.Kind)
+
+    set, err := entry.New(share.Options{Capacity: params.capacity})
+    assert.Assert(err == nil, fmt.Sprint(err))
+    deque := set.(registry.Deque)
+
+    var seq uint64 = 0 // Next value pushed, only used by the owner
+    var push_sum_total uint64 = 0
+
+    { // DataSet initialization
+        fmt.Printf("Adding %v entries to set...", params.initial)
+        for ; seq < uint64(params.initial); seq++ {
+            deque.PushBottom(share.Val(seq))
+            push_sum_total += seq
+        }
+        size := set.Size()
+        fmt.Printf(" done.\n")
+        assert.Assert(size == params.initial, fmt.Sprintf("Single-threaded set initialization failed: set size = %v", size))
+    }
+
+    var barrier sync.WaitGroup
+    owner := func(stats *stats_t) {
+        var xorshf xorshift.State
+        xorshf.Init()
+        for volatile.ReadInt32(&running) != 0 {
+            if uint(xorshf.Intn(100)) < params.push {
+                deque.PushBottom(share.Val(seq))
+                stats.push_count++
+                stats.push_sum += seq
+                seq++
+            } else {
+                val, ok := deque.PopBottom()
+                if ok {
+                    stats.pop_count_succ++
+                    stats.pop_sum += uint64(val)
+                }
+                stats.pop_count++
+            }
+        }
+    }
+    thief := func(stats *stats_t) {
+        next := uint64(0) // Smallest sequence number the next steal may return
+        for volatile.ReadInt32(&running) != 0 {
+            val, ok := deque.Steal()
+            if ok {
+                stats.steal_count_succ++
+                stats.steal_sum += uint64(val)
+                if uint64(val) < next {
+                    stats.order_errors++
+                }
+                next = uint64(val) + 1
+            }
+            stats.steal_count++
+        }
+    }
+
+    var total stats_t
+    var total_lock sync.Mutex
+
+    { // Creating threads
+        barrier.Add(1)
+        fmt.Print("Creating threads: ")
+        for i := uint(0); i <= params.num_thieves; i++ {
+            if i == 0 {
+                fmt.Print(i)
+            } else {
+                fmt.Print(", ", i)
+            }
+            id := i // The owner is thread 0
+            thread.Spawn(func() {
+                stats := new(stats_t)
+                barrier.Wait()
+
+                if id == 0 {
+                    owner(stats)
+                } else {
+                    thief(stats)
+                }
+
+                // Global stats update
+                total_lock.Lock()
+                total.push_count += stats.push_count
+                total.push_sum += stats.push_sum
+                total.pop_count += stats.pop_count
+                total.pop_count_succ += stats.pop_count_succ
+                total.pop_sum += stats.pop_sum
+                total.steal_count += stats.steal_count
+                total.steal_count_succ += stats.steal_count_succ
+                total.steal_sum += stats.steal_sum
+                total.order_errors += stats.order_errors
+                total_lock.Unlock()
+            })
+        }
+        fmt.Println()
+    }
+
+    var actual_duration float64 // Actual test duration (in ms)
+
+    { // Running threads
+        fmt.Println("*** RUNNING ***")
+        atomic.StoreInt32(&running, 1)
+        start_time := time.Now()
+        barrier.Done() // Threads were waiting for it
+
+        <-time.After(time.Duration(params.duration) * time.Millisecond) // Wait for duration
+
+        atomic.StoreInt32(&running, 0)
+        actual_duration = float64(time.Since(start_time).Nanoseconds()) * float64(time.Nanosecond) / float64(time.Millisecond)
+        thread.WaitAll() // Wait for threads to update global statistics
+        fmt.Println("*** STOPPED ***")
+    }
+
+    { // Check and print global statistics
+        push_sum_total += total.push_sum
+        taken_sum_total := total.pop_sum + total.steal_sum
+
+        { // Assert set size
+            ssize := uint64(set.Size())
+            wsize := uint64(params.initial) + total.push_count - total.pop_count_succ - total.steal_count_succ
+            assert.Assert(wsize == ssize, fmt.Sprintf("WRONG set size: %v instead of %v", ssize, wsize))
+        }
+
+        { // Drain the deque, then assert that every value was taken once
+            for {
+                val, ok := deque.Steal()
+                if !ok {
+                    break
+                }
+                taken_sum_total += uint64(val)
+            }
+            assert.Assert(set.Size() == 0, "WRONG set size after draining")
+            assert.Assert(taken_sum_total == push_sum_total, "WRONG values: some values were lost, duplicated or corrupted")
+            assert.Assert(total.order_errors == 0, fmt.Sprintf("WRONG order: %v values stolen out of order", total.order_errors))
+        }
+
+        owner_total := total.push_count + total.pop_count
+        pop_perc_succ := 100.0 * float64(total.pop_count_succ) / float64(max(total.pop_count, 1))
+        steal_perc_succ := 100.0 * float64(total.steal_count_succ) / float64(max(total.steal_count, 1))
+
+        fmt.Printf("      : %-10s | %-10s | %-11s\n", "total", "success", "succ %")
+        fmt.Printf("push  : %-10v | %-10v | %10.1f%%\n", total.push_count, total.push_count, 100.0)
+        fmt.Printf("pop   : %-10v | %-10v | %10.1f%%\n", total.pop_count, total.pop_count_succ, pop_perc_succ)
+        fmt.Printf("steal : %-10v | %-10v | %10.1f%%\n", total.steal_count, total.steal_count_succ, steal_perc_succ)
+
+        owner_throughput := float64(owner_total) * 1000.0 / actual_duration
+        steal_throughput := float64(total.steal_count_succ) * 1000.0 / actual_duration
+        fmt.Printf("#owner %.3f Mops\n", owner_throughput / 1e6)
+        fmt.Printf("#steals %v\t(%-10.0f\n", params.num_thieves, steal_throughput)
+        fmt.Printf("#Mops %.3f\n", steal_throughput / 1e6)
+    }
+
+    set.Destroy()
+}
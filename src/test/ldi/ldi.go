@@ -26,19 +26,21 @@
 package main
 
 import (
-    "dataset"
     "flag"
     "fmt"
     "math"
     "sync"
     "sync/atomic"
-    "tools/share"
     "time"
-    "tools/assert"
-    "tools/thread"
-    "tools/volatile"
-    "tools/xorshift"
     "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/registry"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/assert"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/thread"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
 )
 
 const (
@@ -98,9 +100,15 @@ func main() {
     var update uint
     var put uint
     var load_factor uint
+    var opts share.Options
     var only_results bool
+    var name string
+    var list bool
+    var entry registry.Entry
 
     { // Parameters
+        flag.StringVar(&name, "a", "linkedlist_lazy", "Name of the algorithm to test")
+        flag.BoolVar(&list, "list", false, "List the available algorithms and exit")
         flag.UintVar(&duration, "d", 1000, "Test duration in milliseconds")
         flag.UintVar(&initial, "i", 1024, "Number of elements to insert before test")
         flag.UintVar(&num_threads, "n", 4, "Number of threads")
@@ -108,14 +116,26 @@ func main() {
         flag.UintVar(&update, "u", 20, "Percentage of update transactions")
         flag.UintVar(&put, "p", 10, "Percentage of put update transactions (should be less than percentage of updates)")
         flag.UintVar(&load_factor, "c", 1, "Load factor for the hash table")
-        flag.UintVar(&share.Concurrency, "l", 512, "Concurrency level for the hash table")
-        flag.UintVar(&share.NumBuckets, "b", 64, "Amount of buckets for the hash table")
+        flag.UintVar(&opts.Concurrency, "l", 512, "Concurrency level for the hash table")
+        flag.UintVar(&opts.NumBuckets, "b", 64, "Amount of buckets for the hash table")
         flag.BoolVar(&only_results, "o", false, "Only print operation latencies")
         flag.Parse()
 
+        if list {
+            for _, entry := range registry.Entries() {
+                fmt.Printf("%-30s %v\n", entry.Name, entry.Kind)
+            }
+            return
+        }
+
+        var ok bool
+        entry, ok = registry.Lookup(name)
+        assert.Assert(ok, "Unknown algorithm '" + name + "'")
         assert.Assert(num_threads > 0, "The amount of test threads should be a positive integer")
+        err := entry.Testable(num_threads)
+        assert.Assert(err == nil, fmt.Sprint(err))
 
-        if dataset.FindIsDef {
+        if entry.Kind == dataset.KIND_SET {
             assert.Assert(update <= 100, "The update rate should not be greater than 100 (it is a percentage)")
             if put > update {
                 if !only_results {
@@ -143,14 +163,14 @@ func main() {
             }
             initial = temp
         }
-        share.Capacity = initial / load_factor
-        share.LevelMax = log2(initial)
-        if !isPow2(share.Concurrency) {
-            temp := toPow2(share.Concurrency)
+        opts.Capacity = initial / load_factor
+        opts.LevelMax = log2(initial)
+        if !isPow2(opts.Concurrency) {
+            temp := toPow2(opts.Concurrency)
             if !only_results {
-                fmt.Printf("** rounding up concurrency (to make it power of 2): old: %v / new: %v\n", share.Concurrency, temp)
+                fmt.Printf("** rounding up concurrency (to make it power of 2): old: %v / new: %v\n", opts.Concurrency, temp)
             }
-            share.Concurrency = temp
+            opts.Concurrency = temp
         }
         if rng < initial {
             rng = 2 * initial
@@ -219,7 +239,9 @@ func main() {
         }
     }
 
-    set := dataset.New()
+    set, err := entry.New(opts)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    ops := entry.Ops(set)
     var size uint
 
     { // DataSet initialization (kept while not found in test_simple.c)
@@ -227,7 +249,7 @@ func main() {
             fmt.Printf("Adding %v entries to set...", initial)
         }
         for i := initial; i > 0; i-- {
-            set.Insert(share.Key(i), 0)
+            ops.Put(share.Key(i), 0)
         }
         size = set.Size()
         if !only_results {
@@ -245,17 +267,17 @@ func main() {
             key := share.Key(xorshf.Intn(uint32(rng)) + 1)
             if (op < put) {
                 start := time.Now()
-                set.Insert(key, 0)
+                ops.Put(key, 0)
                 stats.put_time += uint64(time.Since(start))
                 stats.put_count++
             } else if (op < update) {
                 start := time.Now()
-                set.Delete(key)
+                ops.Remove(key)
                 stats.remove_time += uint64(time.Since(start))
                 stats.remove_count++
             } else {
                 start := time.Now()
-                set.Find(key)
+                ops.Get(key)
                 stats.get_time += uint64(time.Since(start))
                 stats.get_count++
             }
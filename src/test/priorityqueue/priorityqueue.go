@@ -0,0 +1,268 @@
+/**
+ * @file   priorityqueue.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Priority queue test module, using InsertWithPriority/DeleteMin.
+ * Every value is its own priority, so that the test can check that the
+ * returned pairs are consistent, and that the queue drains in order (for a
+ * relaxed priority queue: that it drains each priority once, none of them
+ * smaller than the one of the element peeked just before).
+**/
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/registry"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/assert"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/thread"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+// -----------------------------------------------------------------------------
+
+// True if the tests are running
+var running int32
+
+// Test parameters
+type params_t struct {
+    duration uint
+    initial uint
+    num_threads uint
+    put uint
+    rang uint
+    opts share.Options
+}
+
+// Thread run statistics
+type stats_t struct {
+    put_count uint64
+    put_count_succ uint64
+    get_count uint64
+    get_count_succ uint64
+    mismatches uint64
+}
+
+// -----------------------------------------------------------------------------
+
+func main() {
+    var params params_t
+    var names string
+    var list bool
+
+    { // Parameters
+        flag.StringVar(&names, "a", "priorityqueue_lotanshavit_lf", "Comma-separated list of priority queues to test, or 'all'")
+        flag.BoolVar(&list, "list", false, "List the available priority queues and exit")
+        flag.UintVar(&params.duration, "d", 1000, "Test duration in milliseconds")
+        flag.UintVar(&params.initial, "i", 1024, "Number of elements to insert before test")
+        flag.UintVar(&params.num_threads, "n", 1, "Number of threads")
+        flag.UintVar(&params.put, "p", 50, "Percentage of insert operations")
+        flag.UintVar(&params.rang, "r", 2048, "Range of integer priorities inserted in set")
+        flag.UintVar(&params.opts.Relaxation, "relax", 0, "Relaxation of the relaxed priority queues (0 for the default)")
+        flag.Parse()
+
+        if list {
+            for _, entry := range registry.Entries() {
+                if entry.Kind == dataset.KIND_PRIORITY_QUEUE {
+                    fmt.Printf("%-30s %v\n", entry.Name, entry.Kind)
+                }
+            }
+            return
+        }
+
+        assert.Assert(params.num_threads > 0, "The amount of test threads should be a positive integer")
+        assert.Assert(params.put <= 100, "The insert rate should not be greater than 100 (it is a percentage)")
+        assert.Assert(params.initial <= params.rang, "The initial amount of elements should not be greater than the range of priorities")
+        fmt.Printf("## Initial: %v / Range: %v / Put: %v%%\n", params.initial, params.rang, params.put)
+    }
+
+    entries, err := registry.Select(names)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    for _, entry := range entries {
+        if entry.Kind != dataset.KIND_PRIORITY_QUEUE {
+            assert.Assert(names == "all", "'" + entry.Name + "' is not a priority queue")
+            continue
+        }
+        run(entry, params)
+    }
+}
+
+/** Run the test on one priority queue.
+ * @param entry  Data structure to test
+ * @param params Test parameters
+**/
+func run(entry registry.Entry, params params_t) {
+    fmt.Printf("### Algorithm: %v (%v)\n", entry.Name, entry.Kind)
+
+    set, err := entry.New(params.opts)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    pq := set.(registry.PriorityQueue)
+    relaxed := false
+    if rpq, ok := set.(registry.RelaxedPriorityQueue); ok {
+        relaxed = rpq.Relaxation() > 1
+        fmt.Printf("Relaxation: %v\n", rpq.Relaxation())
+    }
+
+    { // DataSet initialization
+        fmt.Printf("Adding %v entries to set...", params.initial)
+        var xorshf xorshift.State
+        xorshf.Init()
+        for i := params.initial; i > 0; {
+            key := share.Key(xorshf.Intn(uint32(params.rang)))
+            if pq.InsertWithPriority(key, share.Val(key)) {
+                i--
+            }
+        }
+        size := set.Size()
+        fmt.Printf(" done.\n")
+        assert.Assert(size == params.initial, fmt.Sprintf("Single-threaded set initialization failed: set size = %v", size))
+    }
+
+    var barrier sync.WaitGroup
+    test := func(stats *stats_t) {
+        var xorshf xorshift.State
+        xorshf.Init()
+        for volatile.ReadInt32(&running) != 0 {
+            if uint(xorshf.Intn(100)) < params.put {
+                key := share.Key(xorshf.Intn(uint32(params.rang)))
+                if pq.InsertWithPriority(key, share.Val(key)) {
+                    stats.put_count_succ++
+                }
+                stats.put_count++
+            } else {
+                key, val, ok := pq.DeleteMin()
+                if ok {
+                    if val != share.Val(key) {
+                        stats.mismatches++
+                    }
+                    stats.get_count_succ++
+                }
+                stats.get_count++
+            }
+        }
+    }
+
+    var put_count_total uint64 = 0
+    var put_count_total_succ uint64 = 0
+    var get_count_total uint64 = 0
+    var get_count_total_succ uint64 = 0
+    var mismatches_total uint64 = 0
+
+    { // Creating threads
+        barrier.Add(1)
+        fmt.Print("Creating threads: ")
+        for i := uint(0); i < params.num_threads; i++ {
+            if i == 0 {
+                fmt.Print(i)
+            } else {
+                fmt.Print(", ", i)
+            }
+            thread.Spawn(func() {
+                stats := new(stats_t)
+                barrier.Wait()
+
+                test(stats)
+
+                // Global stats update
+                atomic.AddUint64(&put_count_total, stats.put_count)
+                atomic.AddUint64(&put_count_total_succ, stats.put_count_succ)
+                atomic.AddUint64(&get_count_total, stats.get_count)
+                atomic.AddUint64(&get_count_total_succ, stats.get_count_succ)
+                atomic.AddUint64(&mismatches_total, stats.mismatches)
+            })
+        }
+        fmt.Println()
+    }
+
+    var actual_duration float64 // Actual test duration (in ms)
+
+    { // Running threads
+        fmt.Println("*** RUNNING ***")
+        atomic.StoreInt32(&running, 1)
+        start_time := time.Now()
+        barrier.Done() // Threads were waiting for it
+
+        <-time.After(time.Duration(params.duration) * time.Millisecond) // Wait for duration
+
+        atomic.StoreInt32(&running, 0)
+        actual_duration = float64(time.Since(start_time).Nanoseconds()) * float64(time.Nanosecond) / float64(time.Millisecond)
+        thread.WaitAll() // Wait for threads to update global statistics
+        fmt.Println("*** STOPPED ***")
+    }
+
+    { // Check and print global statistics
+        { // Assert set size
+            ssize := uint64(set.Size())
+            wsize := uint64(params.initial) + put_count_total_succ - get_count_total_succ
+            assert.Assert(wsize == ssize, fmt.Sprintf("WRONG set size: %v instead of %v", ssize, wsize))
+        }
+
+        assert.Assert(mismatches_total == 0, fmt.Sprintf("WRONG pairs: %v deleted values did not match their priority", mismatches_total))
+
+        { // Drain the data structure, then assert that the priorities came in increasing order (once each if relaxed)
+            first := true
+            var last share.Key
+            deleted := make(map[share.Key]bool)
+            for {
+                peek, _, peek_ok := pq.Peek()
+                key, val, ok := pq.DeleteMin()
+                assert.Assert(peek_ok == ok, "WRONG peek: emptiness differs from the one of the following delete")
+                if !ok {
+                    break
+                }
+                assert.Assert(val == share.Val(key), fmt.Sprintf("WRONG pair: value %v for priority %v", val, key))
+                if relaxed {
+                    assert.Assert(peek <= key, fmt.Sprintf("WRONG peek: %v greater than the deleted %v", peek, key))
+                    assert.Assert(!deleted[key], fmt.Sprintf("WRONG order: priority %v deleted twice", key))
+                    deleted[key] = true
+                } else {
+                    assert.Assert(peek == key, fmt.Sprintf("WRONG peek: %v instead of %v", peek, key))
+                    assert.Assert(first || last < key, fmt.Sprintf("WRONG order: priority %v deleted after %v", key, last))
+                }
+                first = false
+                last = key
+            }
+            assert.Assert(set.Size() == 0, "WRONG set size after draining")
+        }
+
+        total := put_count_total + get_count_total
+        put_perc := 100.0 * float64(put_count_total) / float64(total)
+        put_perc_succ := 100.0 * float64(put_count_total_succ) / float64(put_count_total)
+        get_perc := 100.0 * float64(get_count_total) / float64(total)
+        get_perc_succ := 100.0 * float64(get_count_total_succ) / float64(get_count_total)
+
+        fmt.Printf("    : %-10s | %-10s | %-11s | %-11s\n", "total", "success", "succ %", "total %")
+        fmt.Printf("put : %-10v | %-10v | %10.1f%% | %10.1f%%\n", put_count_total, put_count_total_succ, put_perc_succ, put_perc)
+        fmt.Printf("get : %-10v | %-10v | %10.1f%% | %10.1f%%\n", get_count_total, get_count_total_succ, get_perc_succ, get_perc)
+
+        throughput := float64(total) * 1000.0 / actual_duration
+        fmt.Printf("#txs %v\t(%-10.0f\n", params.num_threads, throughput)
+        fmt.Printf("#Mops %.3f\n", throughput / 1e6)
+    }
+
+    set.Destroy()
+}
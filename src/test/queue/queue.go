@@ -0,0 +1,362 @@
+/**
+ * @file   queue.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Queue and stack test module, using Enqueue/Dequeue and Push/Pop.
+ * Every inserted value is tagged with its producer and a sequence number, so
+ * that the test can check that no value is lost or duplicated, and that the
+ * queues keep the FIFO order of each producer.
+ * A buffered Go channel can also be tested as a baseline, with the same
+ * workload (an enqueue failing when the channel is full).
+**/
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/registry"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/assert"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/thread"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+const (
+    seq_bits = 40 // Amount of bits of a value holding the sequence number
+)
+
+// -----------------------------------------------------------------------------
+
+// True if the tests are running
+var running int32
+
+// Test parameters
+type params_t struct {
+    duration uint
+    initial uint
+    num_threads uint
+    put uint
+    channel uint
+}
+
+// Thread run statistics
+type stats_t struct {
+    put_count uint64
+    put_count_succ uint64
+    put_sum uint64
+    get_count uint64
+    get_count_succ uint64
+    get_sum uint64
+    order_errors uint64
+}
+
+// Uniform access to a queue, a stack or a channel
+type fifo_t struct {
+    kind dataset.Kind
+    put func(val share.Val) bool
+    get func() (share.Val, bool)
+    size func() uint
+    destroy func()
+}
+
+// -----------------------------------------------------------------------------
+
+func make_val(producer uint, seq uint64) share.Val {
+    return share.Val(uint64(producer) << seq_bits | seq)
+}
+
+func split_val(val share.Val) (producer uint, seq uint64) {
+    return uint(uint64(val) >> seq_bits), uint64(val) & (1 << seq_bits - 1)
+}
+
+/** Get the uniform access to a registered queue or stack.
+ * @param entry Queue or stack to test
+ * @return Access to a new instance
+**/
+func entry_fifo(entry registry.Entry) fifo_t {
+    set, err := entry.New(share.Options{})
+    assert.Assert(err == nil, fmt.Sprint(err))
+    fifo := fifo_t{kind: entry.Kind, size: set.Size, destroy: set.Destroy}
+    if entry.Kind == dataset.KIND_QUEUE {
+        queue := set.(registry.Queue)
+        fifo.put = func(val share.Val) bool { queue.Enqueue(val); return true }
+        fifo.get = queue.Dequeue
+    } else {
+        stack := set.(registry.Stack)
+        fifo.put = func(val share.Val) bool { stack.Push(val); return true }
+        fifo.get = stack.Pop
+    }
+    return fifo
+}
+
+/** Get the uniform access to a buffered Go channel, never blocking.
+ * @param capacity Capacity of the channel
+ * @return Access to a new channel
+**/
+func channel_fifo(capacity uint) fifo_t {
+    channel := make(chan share.Val, capacity)
+    return fifo_t{
+        kind: dataset.KIND_QUEUE,
+        put: func(val share.Val) bool {
+            select {
+            case channel <- val:
+                return true
+            default: // Full
+                return false
+            }
+        },
+        get: func() (share.Val, bool) {
+            select {
+            case val := <-channel:
+                return val, true
+            default: // Empty
+                return 0, false
+            }
+        },
+        size: func() uint { return uint(len(channel)) },
+        destroy: func() {},
+    }
+}
+
+/** Check the order of a value taken from the data structure.
+ * @param kind Kind of the tested data structure
+ * @param last Last sequence number seen for each producer (+1, 0 if none)
+ * @param val  Value taken
+ * @return Whether the order is respected
+**/
+func check_order(kind dataset.Kind, last []uint64, val share.Val) bool {
+    producer, seq := split_val(val)
+    if producer >= uint(len(last)) {
+        return false
+    }
+    ok := true
+    if kind == dataset.KIND_QUEUE && last[producer] > seq { // Values of a same producer are dequeued in order
+        ok = false
+    }
+    last[producer] = seq + 1
+    return ok
+}
+
+// -----------------------------------------------------------------------------
+
+func main() {
+    var params params_t
+    var names string
+    var list bool
+
+    { // Parameters
+        flag.StringVar(&names, "a", "queue_ms_lf", "Comma-separated list of queues/stacks to test, or 'all'")
+        flag.BoolVar(&list, "list", false, "List the available queues/stacks and exit")
+        flag.UintVar(&params.duration, "d", 1000, "Test duration in milliseconds")
+        flag.UintVar(&params.initial, "i", 1024, "Number of elements to insert before test")
+        flag.UintVar(&params.num_threads, "n", 1, "Number of threads")
+        flag.UintVar(&params.put, "p", 50, "Percentage of enqueue/push operations")
+        flag.UintVar(&params.channel, "chan", 0, "Capacity of a buffered Go channel to test as well, as a baseline (0 for none)")
+        flag.Parse()
+
+        if list {
+            for _, entry := range registry.Entries() {
+                if entry.Kind == dataset.KIND_QUEUE || entry.Kind == dataset.KIND_STACK {
+                    fmt.Printf("%-30s %v\n", entry.Name, entry.Kind)
+                }
+            }
+            return
+        }
+
+        assert.Assert(params.num_threads > 0, "The amount of test threads should be a positive integer")
+        assert.Assert(params.put <= 100, "The enqueue/push rate should not be greater than 100 (it is a percentage)")
+        fmt.Printf("## Initial: %v / Put: %v%%\n", params.initial, params.put)
+    }
+
+    entries, err := registry.Select(names)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    for _, entry := range entries {
+        if entry.Kind != dataset.KIND_QUEUE && entry.Kind != dataset.KIND_STACK {
+            assert.Assert(names == "all", "'" + entry.Name + "' is neither a queue nor a stack")
+            continue
+        }
+        run(entry.Name, entry_fifo(entry), params)
+    }
+    if params.channel > 0 {
+        assert.Assert(params.channel >= params.initial, "The capacity of the channel should not be less than the number of initial elements")
+        run("go_channel", channel_fifo(params.channel), params)
+    }
+}
+
+/** Run the test on one queue, stack or channel.
+ * @param name   Name of the data structure
+ * @param fifo   Access to the data structure
+ * @param params Test parameters
+**/
+func run(name string, fifo fifo_t, params params_t) {
+    fmt.Printf("### Algorithm: %v (%v)\n", name, fifo.kind)
+
+    var put_sum_total uint64 = 0 // Producer 0 is the initialization
+
+    { // DataSet initialization
+        fmt.Printf("Adding %v entries to set...", params.initial)
+        for i := uint64(0); i < uint64(params.initial); i++ {
+            val := make_val(0, i)
+            assert.Assert(fifo.put(val), "Single-threaded set initialization failed: full set")
+            put_sum_total += uint64(val)
+        }
+        size := fifo.size()
+        fmt.Printf(" done.\n")
+        assert.Assert(size == params.initial, fmt.Sprintf("Single-threaded set initialization failed: set size = %v", size))
+    }
+
+    var barrier sync.WaitGroup
+    test := func(id uint, stats *stats_t) {
+        var xorshf xorshift.State
+        xorshf.Init()
+        last := make([]uint64, params.num_threads + 1)
+        seq := uint64(0)
+        for volatile.ReadInt32(&running) != 0 {
+            if uint(xorshf.Intn(100)) < params.put {
+                val := make_val(id, seq)
+                if fifo.put(val) {
+                    seq++
+                    stats.put_count_succ++
+                    stats.put_sum += uint64(val)
+                }
+                stats.put_count++
+            } else {
+                val, ok := fifo.get()
+                if ok {
+                    stats.get_count_succ++
+                    stats.get_sum += uint64(val)
+                    if !check_order(fifo.kind, last, val) {
+                        stats.order_errors++
+                    }
+                }
+                stats.get_count++
+            }
+        }
+    }
+
+    var put_count_total uint64 = 0
+    var put_count_total_succ uint64 = 0
+    var get_count_total uint64 = 0
+    var get_count_total_succ uint64 = 0
+    var get_sum_total uint64 = 0
+    var order_errors_total uint64 = 0
+
+    { // Creating threads
+        barrier.Add(1)
+        fmt.Print("Creating threads: ")
+        for i := uint(0); i < params.num_threads; i++ {
+            if i == 0 {
+                fmt.Print(i)
+            } else {
+                fmt.Print(", ", i)
+            }
+            id := i + 1 // Producer id
+            thread.Spawn(func() {
+                stats := new(stats_t)
+                barrier.Wait()
+
+                test(id, stats)
+
+                // Global stats update
+                atomic.AddUint64(&put_count_total, stats.put_count)
+                atomic.AddUint64(&put_count_total_succ, stats.put_count_succ)
+                atomic.AddUint64(&put_sum_total, stats.put_sum)
+                atomic.AddUint64(&get_count_total, stats.get_count)
+                atomic.AddUint64(&get_count_total_succ, stats.get_count_succ)
+                atomic.AddUint64(&get_sum_total, stats.get_sum)
+                atomic.AddUint64(&order_errors_total, stats.order_errors)
+            })
+        }
+        fmt.Println()
+    }
+
+    var actual_duration float64 // Actual test duration (in ms)
+
+    { // Running threads
+        fmt.Println("*** RUNNING ***")
+        atomic.StoreInt32(&running, 1)
+        start_time := time.Now()
+        barrier.Done() // Threads were waiting for it
+
+        <-time.After(time.Duration(params.duration) * time.Millisecond) // Wait for duration
+
+        atomic.StoreInt32(&running, 0)
+        actual_duration = float64(time.Since(start_time).Nanoseconds()) * float64(time.Nanosecond) / float64(time.Millisecond)
+        thread.WaitAll() // Wait for threads to update global statistics
+        fmt.Println("*** STOPPED ***")
+    }
+
+    { // Check and print global statistics
+        { // Assert set size
+            ssize := uint64(fifo.size())
+            wsize := uint64(params.initial) + put_count_total_succ - get_count_total_succ
+            assert.Assert(wsize == ssize, fmt.Sprintf("WRONG set size: %v instead of %v", ssize, wsize))
+        }
+
+        { // Drain the data structure, then assert that every value was taken once, in order
+            last := make([]uint64, params.num_threads + 1)
+            if fifo.kind == dataset.KIND_STACK { // Remaining values of a same producer are popped in reverse order
+                for i := range last {
+                    last[i] = ^uint64(0)
+                }
+            }
+            for {
+                val, ok := fifo.get()
+                if !ok {
+                    break
+                }
+                get_sum_total += uint64(val)
+                producer, seq := split_val(val)
+                assert.Assert(producer < uint(len(last)), fmt.Sprintf("WRONG value %v", val))
+                if fifo.kind == dataset.KIND_QUEUE {
+                    assert.Assert(check_order(fifo.kind, last, val), fmt.Sprintf("WRONG order: value %v of producer %v dequeued too late", seq, producer))
+                } else {
+                    assert.Assert(seq < last[producer], fmt.Sprintf("WRONG order: value %v of producer %v popped too late", seq, producer))
+                    last[producer] = seq
+                }
+            }
+            assert.Assert(fifo.size() == 0, "WRONG set size after draining")
+            assert.Assert(get_sum_total == put_sum_total, "WRONG values: some values were lost, duplicated or corrupted")
+            assert.Assert(order_errors_total == 0, fmt.Sprintf("WRONG order: %v values taken out of the order of their producer", order_errors_total))
+        }
+
+        total := put_count_total + get_count_total
+        put_perc := 100.0 * float64(put_count_total) / float64(total)
+        get_perc := 100.0 * float64(get_count_total) / float64(total)
+        put_perc_succ := 100.0 * float64(put_count_total_succ) / float64(put_count_total)
+        get_perc_succ := 100.0 * float64(get_count_total_succ) / float64(get_count_total)
+
+        fmt.Printf("    : %-10s | %-10s | %-11s | %-11s\n", "total", "success", "succ %", "total %")
+        fmt.Printf("put : %-10v | %-10v | %10.1f%% | %10.1f%%\n", put_count_total, put_count_total_succ, put_perc_succ, put_perc)
+        fmt.Printf("get : %-10v | %-10v | %10.1f%% | %10.1f%%\n", get_count_total, get_count_total_succ, get_perc_succ, get_perc)
+
+        throughput := float64(total) * 1000.0 / actual_duration
+        fmt.Printf("#txs %v\t(%-10.0f\n", params.num_threads, throughput)
+        fmt.Printf("#Mops %.3f\n", throughput / 1e6)
+    }
+
+    fifo.destroy()
+}
@@ -0,0 +1,305 @@
+/**
+ * @file   rank.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Rank error test module, for the (relaxed) priority queues: besides the
+ * throughput, measures how far from the minimum each DeleteMin returned.
+ * Every successful operation is logged with a stamp from a global counter,
+ * taken before an insertion starts and after a deletion returns, so that an
+ * element is always stamped as inserted before it is deleted. The logs are
+ * then replayed in stamp order on an exact multiset of priorities, the rank
+ * error of a deletion being the amount of smaller priorities present.
+ * The exact priority queues thus show a (small) rank error as well, from the
+ * operations running concurrently between their linearization and their stamp.
+**/
+
+package main
+
+import (
+    "cmp"
+    "flag"
+    "fmt"
+    "slices"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/registry"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/assert"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/thread"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+// -----------------------------------------------------------------------------
+
+// True if the tests are running
+var running int32
+
+// Stamp of the next logged operation
+var clock atomic.Uint64
+
+// Test parameters
+type params_t struct {
+    duration uint
+    initial uint
+    num_threads uint
+    put uint
+    rang uint
+    opts share.Options
+}
+
+// Logged operation
+type record_t struct {
+    stamp uint64
+    key share.Key
+    put bool // Insertion, or deletion
+}
+
+// Thread run statistics
+type stats_t struct {
+    put_count uint64
+    put_count_succ uint64
+    get_count uint64
+    get_count_succ uint64
+    mismatches uint64
+    log []record_t // Successful operations
+}
+
+// Counts of the priorities present, as a Fenwick tree
+type fenwick_t []int64
+
+// -----------------------------------------------------------------------------
+
+func (tree fenwick_t) add(key share.Key, delta int64) {
+    for i := int(key) + 1; i <= len(tree); i += i & -i {
+        tree[i - 1] += delta
+    }
+}
+
+func (tree fenwick_t) count_less(key share.Key) int64 { // Amount of priorities smaller than 'key'
+    res := int64(0)
+    for i := int(key); i > 0; i -= i & -i {
+        res += tree[i - 1]
+    }
+    return res
+}
+
+// -----------------------------------------------------------------------------
+
+func main() {
+    var params params_t
+    var names string
+    var list bool
+
+    { // Parameters
+        flag.StringVar(&names, "a", "priorityqueue_multiqueue", "Comma-separated list of priority queues to test, or 'all'")
+        flag.BoolVar(&list, "list", false, "List the available priority queues and exit")
+        flag.UintVar(&params.duration, "d", 1000, "Test duration in milliseconds")
+        flag.UintVar(&params.initial, "i", 1024, "Number of elements to insert before test")
+        flag.UintVar(&params.num_threads, "n", 1, "Number of threads")
+        flag.UintVar(&params.put, "p", 50, "Percentage of insert operations")
+        flag.UintVar(&params.rang, "r", 2048, "Range of integer priorities inserted in set")
+        flag.UintVar(&params.opts.Relaxation, "relax", 0, "Relaxation of the relaxed priority queues (0 for the default)")
+        flag.Parse()
+
+        if list {
+            for _, entry := range registry.Entries() {
+                if entry.Kind == dataset.KIND_PRIORITY_QUEUE {
+                    fmt.Printf("%-30s %v\n", entry.Name, entry.Kind)
+                }
+            }
+            return
+        }
+
+        assert.Assert(params.num_threads > 0, "The amount of test threads should be a positive integer")
+        assert.Assert(params.put <= 100, "The insert rate should not be greater than 100 (it is a percentage)")
+        assert.Assert(params.initial <= params.rang, "The initial amount of elements should not be greater than the range of priorities")
+        fmt.Printf("## Initial: %v / Range: %v / Put: %v%%\n", params.initial, params.rang, params.put)
+    }
+
+    entries, err := registry.Select(names)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    for _, entry := range entries {
+        if entry.Kind != dataset.KIND_PRIORITY_QUEUE {
+            assert.Assert(names == "all", "'" + entry.Name + "' is not a priority queue")
+            continue
+        }
+        run(entry, params)
+    }
+}
+
+/** Run the test on one priority queue.
+ * @param entry  Data structure to test
+ * @param params Test parameters
+**/
+func run(entry registry.Entry, params params_t) {
+    fmt.Printf("### Algorithm: %v (%v)\n", entry.Name, entry.Kind)
+
+    set, err := entry.New(params.opts)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    pq := set.(registry.PriorityQueue)
+    if rpq, ok := set.(registry.RelaxedPriorityQueue); ok {
+        fmt.Printf("Relaxation: %v\n", rpq.Relaxation())
+    }
+
+    present := make(fenwick_t, params.rang) // Priorities present, as replayed from the logs
+
+    { // DataSet initialization
+        fmt.Printf("Adding %v entries to set...", params.initial)
+        var xorshf xorshift.State
+        xorshf.Init()
+        for i := params.initial; i > 0; {
+            key := share.Key(xorshf.Intn(uint32(params.rang)))
+            if pq.InsertWithPriority(key, share.Val(key)) {
+                present.add(key, 1)
+                i--
+            }
+        }
+        size := set.Size()
+        fmt.Printf(" done.\n")
+        assert.Assert(size == params.initial, fmt.Sprintf("Single-threaded set initialization failed: set size = %v", size))
+    }
+
+    var barrier sync.WaitGroup
+    test := func(stats *stats_t) {
+        var xorshf xorshift.State
+        xorshf.Init()
+        for volatile.ReadInt32(&running) != 0 {
+            if uint(xorshf.Intn(100)) < params.put {
+                key := share.Key(xorshf.Intn(uint32(params.rang)))
+                stamp := clock.Add(1) // Before the insertion can be seen
+                if pq.InsertWithPriority(key, share.Val(key)) {
+                    stats.log = append(stats.log, record_t{stamp, key, true})
+                    stats.put_count_succ++
+                }
+                stats.put_count++
+            } else {
+                key, val, ok := pq.DeleteMin()
+                if ok {
+                    stats.log = append(stats.log, record_t{clock.Add(1), key, false})
+                    if val != share.Val(key) {
+                        stats.mismatches++
+                    }
+                    stats.get_count_succ++
+                }
+                stats.get_count++
+            }
+        }
+    }
+
+    var total stats_t
+    var total_lock sync.Mutex
+
+    { // Creating threads
+        barrier.Add(1)
+        fmt.Print("Creating threads: ")
+        for i := uint(0); i < params.num_threads; i++ {
+            if i == 0 {
+                fmt.Print(i)
+            } else {
+                fmt.Print(", ", i)
+            }
+            thread.Spawn(func() {
+                stats := new(stats_t)
+                barrier.Wait()
+
+                test(stats)
+
+                // Global stats update
+                total_lock.Lock()
+                total.put_count += stats.put_count
+                total.put_count_succ += stats.put_count_succ
+                total.get_count += stats.get_count
+                total.get_count_succ += stats.get_count_succ
+                total.mismatches += stats.mismatches
+                total.log = append(total.log, stats.log...)
+                total_lock.Unlock()
+            })
+        }
+        fmt.Println()
+    }
+
+    var actual_duration float64 // Actual test duration (in ms)
+
+    { // Running threads
+        fmt.Println("*** RUNNING ***")
+        atomic.StoreInt32(&running, 1)
+        start_time := time.Now()
+        barrier.Done() // Threads were waiting for it
+
+        <-time.After(time.Duration(params.duration) * time.Millisecond) // Wait for duration
+
+        atomic.StoreInt32(&running, 0)
+        actual_duration = float64(time.Since(start_time).Nanoseconds()) * float64(time.Nanosecond) / float64(time.Millisecond)
+        thread.WaitAll() // Wait for threads to update global statistics
+        fmt.Println("*** STOPPED ***")
+    }
+
+    { // Check and print global statistics
+        { // Assert set size
+            ssize := uint64(set.Size())
+            wsize := uint64(params.initial) + total.put_count_succ - total.get_count_succ
+            assert.Assert(wsize == ssize, fmt.Sprintf("WRONG set size: %v instead of %v", ssize, wsize))
+        }
+
+        assert.Assert(total.mismatches == 0, fmt.Sprintf("WRONG pairs: %v deleted values did not match their priority", total.mismatches))
+
+        ranks := make([]int64, 0, total.get_count_succ)
+        { // Replay the logs in stamp order, and collect the rank error of each deletion
+            slices.SortFunc(total.log, func(a record_t, b record_t) int { return cmp.Compare(a.stamp, b.stamp) })
+            for _, rec := range total.log {
+                if rec.put {
+                    present.add(rec.key, 1)
+                    continue
+                }
+                assert.Assert(present.count_less(rec.key + 1) > present.count_less(rec.key), fmt.Sprintf("WRONG delete: priority %v was not present", rec.key))
+                ranks = append(ranks, present.count_less(rec.key))
+                present.add(rec.key, -1)
+            }
+            slices.Sort(ranks)
+        }
+
+        total_ops := total.put_count + total.get_count
+        put_perc := 100.0 * float64(total.put_count) / float64(max(total_ops, 1))
+        put_perc_succ := 100.0 * float64(total.put_count_succ) / float64(max(total.put_count, 1))
+        get_perc := 100.0 * float64(total.get_count) / float64(max(total_ops, 1))
+        get_perc_succ := 100.0 * float64(total.get_count_succ) / float64(max(total.get_count, 1))
+
+        fmt.Printf("    : %-10s | %-10s | %-11s | %-11s\n", "total", "success", "succ %", "total %")
+        fmt.Printf("put : %-10v | %-10v | %10.1f%% | %10.1f%%\n", total.put_count, total.put_count_succ, put_perc_succ, put_perc)
+        fmt.Printf("get : %-10v | %-10v | %10.1f%% | %10.1f%%\n", total.get_count, total.get_count_succ, get_perc_succ, get_perc)
+
+        if len(ranks) > 0 {
+            sum := int64(0)
+            for _, rank := range ranks {
+                sum += rank
+            }
+            fmt.Printf("rank: mean %.2f | median %v | p99 %v | max %v\n", float64(sum) / float64(len(ranks)), ranks[len(ranks) / 2], ranks[len(ranks) * 99 / 100], ranks[len(ranks) - 1])
+        }
+
+        throughput := float64(total_ops) * 1000.0 / actual_duration
+        fmt.Printf("#txs %v\t(%-10.0f\n", params.num_threads, throughput)
+        fmt.Printf("#Mops %.3f\n", throughput / 1e6)
+    }
+
+    set.Destroy()
+}
@@ -26,19 +26,21 @@
 package main
 
 import (
-    "dataset"
     "flag"
     "fmt"
     "strconv"
     "sync"
     "sync/atomic"
-    "tools/share"
     "time"
-    "tools/assert"
-    "tools/thread"
-    "tools/volatile"
-    "tools/xorshift"
     "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/registry"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/assert"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/thread"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
 )
 
 // -----------------------------------------------------------------------------
@@ -46,6 +48,21 @@ import (
 // True if the tests are running
 var running int32
 
+// Test parameters
+type params_t struct {
+    duration uint
+    initial uint
+    num_threads uint
+    rng uint
+    update uint
+    put uint
+    ranges uint
+    span uint
+    atomic bool
+    bulk bool
+    opts share.Options
+}
+
 // Thread run statistics
 type stats_t struct {
     putting_count uint64
@@ -54,6 +71,10 @@ type stats_t struct {
     getting_count_succ uint64
     removing_count uint64
     removing_count_succ uint64
+    ranging_count uint64
+    ranging_count_succ uint64
+    ranging_keys uint64
+    ranging_errors uint64
 }
 
 // -----------------------------------------------------------------------------
@@ -85,84 +106,137 @@ func log2(x uint) uint {
 // -----------------------------------------------------------------------------
 
 func main() {
-    var duration uint
-    var initial uint
-    var num_threads uint
-    var rng uint
-    var update uint
-    var put uint
+    var params params_t
     var load_factor uint
+    var names string
+    var list bool
 
     { // Parameters
-        flag.UintVar(&duration, "d", 1000, "Test duration in milliseconds")
-        flag.UintVar(&initial, "i", 1024, "Number of elements to insert before test")
-        flag.UintVar(&num_threads, "n", 1, "Number of threads")
-        flag.UintVar(&rng, "r", 2048, "Range of integer values inserted in set")
-        flag.UintVar(&update, "u", 20, "Percentage of update transactions")
-        flag.UintVar(&put, "p", 10, "Percentage of put update transactions (should be less than percentage of updates)")
+        flag.StringVar(&names, "a", "linkedlist_lazy", "Comma-separated list of algorithms to test, or 'all'")
+        flag.BoolVar(&list, "list", false, "List the available algorithms and exit")
+        flag.UintVar(&params.duration, "d", 1000, "Test duration in milliseconds")
+        flag.UintVar(&params.initial, "i", 1024, "Number of elements to insert before test")
+        flag.UintVar(&params.num_threads, "n", 1, "Number of threads")
+        flag.UintVar(&params.rng, "r", 2048, "Range of integer values inserted in set")
+        flag.UintVar(&params.update, "u", 20, "Percentage of update transactions")
+        flag.UintVar(&params.put, "p", 10, "Percentage of put update transactions (should be less than percentage of updates)")
+        flag.UintVar(&params.ranges, "q", 0, "Percentage of range queries (ordered sets only)")
+        flag.UintVar(&params.span, "s", 64, "Width of the key range covered by a range query")
+        flag.BoolVar(&params.atomic, "atomic", false, "Use the atomic range queries, where available")
+        flag.BoolVar(&params.opts.AtomicSize, "size", false, "Count the elements in striped counters, where available")
+        flag.BoolVar(&params.bulk, "bulk", true, "Load the initial elements at once, where available")
         flag.UintVar(&load_factor, "c", 1, "Load factor for the hash table")
-        flag.UintVar(&share.Concurrency, "l", 512, "Concurrency level for the hash table")
-        flag.UintVar(&share.NumBuckets, "b", 64, "Amount of buckets for the hash table")
+        flag.UintVar(&params.opts.Concurrency, "l", 512, "Concurrency level for the hash table")
+        flag.UintVar(&params.opts.NumBuckets, "b", 64, "Amount of buckets for the hash table")
         flag.Parse()
 
-        assert.Assert(num_threads > 0, "The amount of test threads should be a positive integer")
-
-        if dataset.FindIsDef {
-            assert.Assert(update <= 100, "The update rate should not be greater than 100 (it is a percentage)")
-            if put > update {
-                fmt.Printf("** limiting put rate to update rate: old: %v / new: %v\n", put, update)
-                put = update
-            }
-        } else {
-            assert.Assert(update != 0, "The update rate should not be null for a non-searchable dataset")
-            if put > 100 {
-                fmt.Printf("** limiting put rate to update rate: old: %v / new: 100\n", put)
-                put = 100
-            } else {
-                put = put * 100 / update // Scale put too
+        if list {
+            for _, entry := range registry.Entries() {
+                fmt.Printf("%-30s %v\n", entry.Name, entry.Kind)
             }
-            update = 100
+            return
         }
 
-        if !isPow2(initial) {
-            temp := toPow2(initial)
-            fmt.Printf("** rounding up initial (to make it power of 2): old: %v / new: %v\n", initial, temp)
-            initial = temp
+        assert.Assert(params.num_threads > 0, "The amount of test threads should be a positive integer")
+
+        if !isPow2(params.initial) {
+            temp := toPow2(params.initial)
+            fmt.Printf("** rounding up initial (to make it power of 2): old: %v / new: %v\n", params.initial, temp)
+            params.initial = temp
         }
-        share.Capacity = initial / load_factor
-        share.LevelMax = log2(initial)
-        if !isPow2(share.Concurrency) {
-            temp := toPow2(share.Concurrency)
-            fmt.Printf("** rounding up concurrency (to make it power of 2): old: %v / new: %v\n", share.Concurrency, temp)
-            share.Concurrency = temp
+        params.opts.Capacity = params.initial / load_factor
+        params.opts.LevelMax = log2(params.initial)
+        if !isPow2(params.opts.Concurrency) {
+            temp := toPow2(params.opts.Concurrency)
+            fmt.Printf("** rounding up concurrency (to make it power of 2): old: %v / new: %v\n", params.opts.Concurrency, temp)
+            params.opts.Concurrency = temp
         }
-        if rng < initial {
-            rng = 2 * initial
+        if params.rng < params.initial {
+            params.rng = 2 * params.initial
         }
-        fmt.Printf("## Initial: %v / Range: %v\n", initial, rng)
+        fmt.Printf("## Initial: %v / Range: %v\n", params.initial, params.rng)
         {
-            var kb float64 = float64(initial) * float64(unsafe.Sizeof(uint(0))) / 1024
+            var kb float64 = float64(params.initial) * float64(unsafe.Sizeof(uint(0))) / 1024
             var mb float64 = kb / 1024
             fmt.Printf("Sizeof initial: %.2f KB = %.2f MB\n", kb, mb)
         }
-        if !isPow2(rng) {
-            temp := toPow2(rng)
-            fmt.Printf("** rounding up range (to make it power of 2): old: %v / new: %v\n", rng, temp)
-            rng = temp
+        if !isPow2(params.rng) {
+            temp := toPow2(params.rng)
+            fmt.Printf("** rounding up range (to make it power of 2): old: %v / new: %v\n", params.rng, temp)
+            params.rng = temp
+        }
+    }
+
+    entries, err := registry.Select(names)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    for _, entry := range entries {
+        if err := entry.Testable(params.num_threads); err != nil {
+            assert.Assert(names == "all", fmt.Sprint(err))
+            continue
+        }
+        run(entry, params)
+    }
+}
+
+/** Run the test on one data structure.
+ * @param entry  Data structure to test
+ * @param params Test parameters
+**/
+func run(entry registry.Entry, params params_t) {
+    update := params.update
+    put := params.put
+    ranges := params.ranges
+
+    fmt.Printf("### Algorithm: %v (%v)\n", entry.Name, entry.Kind)
+
+    if entry.Kind == dataset.KIND_SET {
+        assert.Assert(update <= 100, "The update rate should not be greater than 100 (it is a percentage)")
+        if put > update {
+            fmt.Printf("** limiting put rate to update rate: old: %v / new: %v\n", put, update)
+            put = update
+        }
+    } else {
+        assert.Assert(update != 0, "The update rate should not be null for a non-searchable dataset")
+        if put > 100 {
+            fmt.Printf("** limiting put rate to update rate: old: %v / new: 100\n", put)
+            put = 100
+        } else {
+            put = put * 100 / update // Scale put too
         }
+        update = 100
     }
 
-    set := dataset.New()
+    set, err := entry.New(params.opts)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    ops := entry.Ops(set)
+    scan := ops.Range
+    if params.atomic && ops.RangeAtomic != nil {
+        scan = ops.RangeAtomic
+    }
+    if scan == nil && ranges > 0 {
+        fmt.Printf("** no range query on this dataset: old: %v / new: 0\n", ranges)
+        ranges = 0
+    }
+    assert.Assert(update + ranges <= 100, "The update and range query rates should not sum to more than 100 (they are percentages)")
     var size uint
 
     { // DataSet initialization (kept while not found in test_simple.c)
-        fmt.Printf("Adding %v entries to set...", initial)
-        for i := initial; i > 0; i-- {
-            set.Insert(share.Key(i), 0)
+        fmt.Printf("Adding %v entries to set...", params.initial)
+        if bulk, ok := set.(registry.BulkSet); ok && params.bulk {
+            pairs := make([]dataset.Pair[share.Key, share.Val], params.initial)
+            for i := range pairs {
+                pairs[i] = dataset.Pair[share.Key, share.Val]{Key: share.Key(i + 1), Val: 0}
+            }
+            err := bulk.BulkLoad(pairs)
+            assert.Assert(err == nil, fmt.Sprint(err))
+        } else {
+            for i := params.initial; i > 0; i-- {
+                ops.Put(share.Key(i), 0)
+            }
         }
         size = set.Size()
         fmt.Printf(" done.\n")
-        assert.Assert(size == initial, fmt.Sprintf("Single-threaded set initialization failed: set size = %v", size))
+        assert.Assert(size == params.initial, fmt.Sprintf("Single-threaded set initialization failed: set size = %v", size))
     }
 
     var barrier sync.WaitGroup
@@ -171,20 +245,38 @@ func main() {
         xorshf.Init()
         for volatile.ReadInt32(&running) != 0 {
             op := uint(xorshf.Intn(100))
-            key := share.Key(xorshf.Intn(uint32(rng)) + 1)
+            key := share.Key(xorshf.Intn(uint32(params.rng)) + 1)
             if (op < put) {
-                if set.Insert(key, 0) {
+                if ops.Put(key, 0) {
                     stats.putting_count_succ++
                 }
                 stats.putting_count++
             } else if (op < update) {
-                _, ok := set.Delete(key)
+                _, ok := ops.Remove(key)
                 if ok {
                     stats.removing_count_succ++
                 }
                 stats.removing_count++
+            } else if (op < update + ranges) {
+                lo := key
+                hi := key + share.Key(params.span) - 1
+                count := uint64(0)
+                prev := lo - 1
+                scan(lo, hi, func(key share.Key, val share.Val) bool {
+                    if key <= prev || key > hi { // Keys must be in range and strictly increasing
+                        stats.ranging_errors++
+                    }
+                    prev = key
+                    count++
+                    return true
+                })
+                if count > 0 {
+                    stats.ranging_count_succ++
+                }
+                stats.ranging_keys += count
+                stats.ranging_count++
             } else {
-                _, ok := set.Find(key)
+                _, ok := ops.Get(key)
                 if ok {
                     stats.getting_count_succ++
                 }
@@ -199,11 +291,15 @@ func main() {
     var getting_count_total_succ uint64 = 0
     var removing_count_total uint64 = 0
     var removing_count_total_succ uint64 = 0
+    var ranging_count_total uint64 = 0
+    var ranging_count_total_succ uint64 = 0
+    var ranging_keys_total uint64 = 0
+    var ranging_errors_total uint64 = 0
 
     { // Creating threads
         barrier.Add(1)
         fmt.Print("Creating threads: ")
-        for i := uint(0); i < num_threads; i++ {
+        for i := uint(0); i < params.num_threads; i++ {
             if i == 0 {
                 fmt.Print(i)
             } else {
@@ -222,6 +318,10 @@ func main() {
                 atomic.AddUint64(&getting_count_total_succ, stats.getting_count_succ)
                 atomic.AddUint64(&removing_count_total, stats.removing_count)
                 atomic.AddUint64(&removing_count_total_succ, stats.removing_count_succ)
+                atomic.AddUint64(&ranging_count_total, stats.ranging_count)
+                atomic.AddUint64(&ranging_count_total_succ, stats.ranging_count_succ)
+                atomic.AddUint64(&ranging_keys_total, stats.ranging_keys)
+                atomic.AddUint64(&ranging_errors_total, stats.ranging_errors)
             })
         }
         fmt.Println()
@@ -235,7 +335,7 @@ func main() {
         start_time := time.Now()
         barrier.Done() // Threads were waiting for it
 
-        <-time.After(time.Duration(duration) * time.Millisecond) // Wait for duration
+        <-time.After(time.Duration(params.duration) * time.Millisecond) // Wait for duration
 
         atomic.StoreInt32(&running, 0)
         actual_duration = float64(time.Since(start_time).Nanoseconds()) * float64(time.Nanosecond) / float64(time.Millisecond)
@@ -246,11 +346,16 @@ func main() {
     { // Print global statistics
         { // Assert set size
             ssize := set.Size()
-            wsize := uint(int64(initial) + int64(putting_count_total_succ) - int64(removing_count_total_succ))
+            wsize := uint(int64(params.initial) + int64(putting_count_total_succ) - int64(removing_count_total_succ))
             assert.Assert(wsize == ssize, "WRONG set size: " + strconv.Itoa(int(ssize)) + " instead of " + strconv.Itoa(int(wsize)))
         }
+        if snap, ok := set.(registry.SnapshotSet); ok { // Assert snapshot size
+            nsize := uint(len(snap.Snapshot()))
+            assert.Assert(nsize == set.Size(), "WRONG snapshot size: " + strconv.Itoa(int(nsize)) + " instead of " + strconv.Itoa(int(set.Size())))
+        }
+        assert.Assert(ranging_errors_total == 0, "WRONG range queries: " + strconv.Itoa(int(ranging_errors_total)) + " keys out of range or out of order")
 
-        total := putting_count_total + getting_count_total + removing_count_total
+        total := putting_count_total + getting_count_total + removing_count_total + ranging_count_total
         putting_perc := 100.0 * (1 - (float64(total - putting_count_total) / float64(total)))
         putting_perc_succ := (1 - float64(putting_count_total - putting_count_total_succ) / float64(putting_count_total)) * 100
         getting_perc := 100.0 * (1 - (float64(total - getting_count_total) / float64(total)))
@@ -262,9 +367,14 @@ func main() {
         fmt.Printf("srch: %-10v | %-10v | %10.1f%% | %10.1f%% | \n", getting_count_total, getting_count_total_succ, getting_perc_succ, getting_perc)
         fmt.Printf("insr: %-10v | %-10v | %10.1f%% | %10.1f%% | %10.1f%%\n", putting_count_total, putting_count_total_succ, putting_perc_succ, putting_perc, (putting_perc * putting_perc_succ) / 100)
         fmt.Printf("rems: %-10v | %-10v | %10.1f%% | %10.1f%% | %10.1f%%\n", removing_count_total, removing_count_total_succ, removing_perc_succ, removing_perc, (removing_perc * removing_perc_succ) / 100)
+        if ranging_count_total > 0 {
+            ranging_perc := 100.0 * float64(ranging_count_total) / float64(total)
+            ranging_perc_succ := 100.0 * float64(ranging_count_total_succ) / float64(ranging_count_total)
+            fmt.Printf("rnge: %-10v | %-10v | %10.1f%% | %10.1f%% | %.1f keys/query\n", ranging_count_total, ranging_count_total_succ, ranging_perc_succ, ranging_perc, float64(ranging_keys_total) / float64(ranging_count_total))
+        }
 
-        throughput := float64(putting_count_total + getting_count_total + removing_count_total) * 1000.0 / actual_duration
-        fmt.Printf("#txs %v\t(%-10.0f\n", num_threads, throughput)
+        throughput := float64(total) * 1000.0 / actual_duration
+        fmt.Printf("#txs %v\t(%-10.0f\n", params.num_threads, throughput)
         fmt.Printf("#Mops %.3f\n", throughput / 1e6)
     }
 
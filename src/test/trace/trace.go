@@ -26,19 +26,21 @@
 package main
 
 import (
-    "dataset"
     "flag"
     "fmt"
     "os"
     "runtime/trace"
     "sync"
     "sync/atomic"
-    "tools/share"
     "time"
-    "tools/assert"
-    "tools/thread"
-    "tools/volatile"
-    "tools/xorshift"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/registry"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/assert"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/share"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/thread"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
 )
 
 // -----------------------------------------------------------------------------
@@ -82,8 +84,14 @@ func main() {
     var update uint
     var put uint
     var load_factor uint
+    var opts share.Options
+    var name string
+    var list bool
+    var entry registry.Entry
 
     { // Parameters
+        flag.StringVar(&name, "a", "linkedlist_lazy", "Name of the algorithm to test")
+        flag.BoolVar(&list, "list", false, "List the available algorithms and exit")
         flag.UintVar(&duration, "d", 1000, "Test duration in milliseconds")
         flag.UintVar(&initial, "i", 1024, "Number of elements to insert before test")
         flag.UintVar(&num_threads, "n", 1, "Number of threads")
@@ -91,13 +99,25 @@ func main() {
         flag.UintVar(&update, "u", 20, "Percentage of update transactions")
         flag.UintVar(&put, "p", 10, "Percentage of put update transactions (should be less than percentage of updates)")
         flag.UintVar(&load_factor, "c", 1, "Load factor for the hash table")
-        flag.UintVar(&share.Concurrency, "l", 512, "Concurrency level for the hash table")
-        flag.UintVar(&share.NumBuckets, "b", 64, "Amount of buckets for the hash table")
+        flag.UintVar(&opts.Concurrency, "l", 512, "Concurrency level for the hash table")
+        flag.UintVar(&opts.NumBuckets, "b", 64, "Amount of buckets for the hash table")
         flag.Parse()
 
+        if list {
+            for _, entry := range registry.Entries() {
+                fmt.Printf("%-30s %v\n", entry.Name, entry.Kind)
+            }
+            return
+        }
+
+        var ok bool
+        entry, ok = registry.Lookup(name)
+        assert.Assert(ok, "Unknown algorithm '" + name + "'")
         assert.Assert(num_threads > 0, "The amount of test threads should be a positive integer")
+        err := entry.Testable(num_threads)
+        assert.Assert(err == nil, fmt.Sprint(err))
 
-        if dataset.FindIsDef {
+        if entry.Kind == dataset.KIND_SET {
             assert.Assert(update <= 100, "The update rate should not be greater than 100 (it is a percentage)")
             if put > update {
                 put = update
@@ -116,11 +136,11 @@ func main() {
             temp := toPow2(initial)
             initial = temp
         }
-        share.Capacity = initial / load_factor
-        share.LevelMax = log2(initial)
-        if !isPow2(share.Concurrency) {
-            temp := toPow2(share.Concurrency)
-            share.Concurrency = temp
+        opts.Capacity = initial / load_factor
+        opts.LevelMax = log2(initial)
+        if !isPow2(opts.Concurrency) {
+            temp := toPow2(opts.Concurrency)
+            opts.Concurrency = temp
         }
         if rng < initial {
             rng = 2 * initial
@@ -131,12 +151,14 @@ func main() {
         }
     }
 
-    set := dataset.New()
+    set, err := entry.New(opts)
+    assert.Assert(err == nil, fmt.Sprint(err))
+    ops := entry.Ops(set)
     var size uint
 
     { // DataSet initialization (kept while not found in test_simple.c)
         for i := initial; i > 0; i-- {
-            set.Insert(share.Key(i), 0)
+            ops.Put(share.Key(i), 0)
         }
         size = set.Size()
         assert.Assert(size == initial, fmt.Sprintf("Single-threaded set initialization failed: set size = %v", size))
@@ -150,11 +172,11 @@ func main() {
             op := uint(xorshf.Intn(100))
             key := share.Key(xorshf.Intn(uint32(rng)) + 1)
             if (op < put) {
-                set.Insert(key, 0)
+                ops.Put(key, 0)
             } else if (op < update) {
-                set.Delete(key)
+                ops.Remove(key)
             } else {
-                set.Find(key)
+                ops.Get(key)
             }
         }
     }
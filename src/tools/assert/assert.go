@@ -25,8 +25,8 @@ package assert
 
 import (
     "fmt"
-    "runtime"
     "path"
+    "runtime"
 )
 
 // -----------------------------------------------------------------------------
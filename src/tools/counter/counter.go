@@ -0,0 +1,98 @@
+/**
+ * @file   counter.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Striped counter, spreading the concurrent updates over several cache lines.
+ * A null counter ignores every update, so that the data structures can keep
+ * one optionally.
+**/
+
+package counter
+
+import (
+    "math/rand/v2"
+    "runtime"
+    "sync/atomic"
+)
+
+const (
+    cache_line_size = 64
+)
+
+// -----------------------------------------------------------------------------
+
+type cell struct {
+    value atomic.Int64
+    _ [cache_line_size - 8]byte // Padding, one cell per cache line
+}
+
+type Striped struct {
+    mask uint32
+    cells []cell
+}
+
+// -----------------------------------------------------------------------------
+
+/** Build a new striped counter, with one stripe per processor (rounded up to a power of 2).
+ * @return Counter, initialized to 0
+**/
+func New() *Striped {
+    stripes := uint32(1)
+    for stripes < uint32(runtime.GOMAXPROCS(0)) {
+        stripes <<= 1
+    }
+    ctr := new(Striped)
+    ctr.mask = stripes - 1
+    ctr.cells = make([]cell, stripes)
+    return ctr
+}
+
+/** Add to the counter, on a random stripe.
+ * @param delta Value to add (may be negative)
+ * @return New value of the stripe, 0 for a nil counter
+**/
+func (ctr *Striped) Add(delta int64) int64 {
+    if ctr == nil {
+        return 0
+    }
+    return ctr.cells[rand.Uint32() & ctr.mask].value.Add(delta)
+}
+
+/** Sum the stripes of the counter.
+ * Exact once the concurrent calls to 'Add' are complete.
+ * @return Current value of the counter
+**/
+func (ctr *Striped) Sum() int64 {
+    var sum int64 = 0
+    for i := range ctr.cells {
+        sum += ctr.cells[i].value.Load()
+    }
+    return sum
+}
+
+/** Get the value of a counter of elements, as returned by 'Size'.
+ * @return Current value of the counter, 0 if negative (a removal being counted before the matching insertion)
+**/
+func (ctr *Striped) Size() uint {
+    sum := ctr.Sum()
+    if sum < 0 {
+        return 0
+    }
+    return uint(sum)
+}
@@ -27,7 +27,8 @@ import (
     "math"
     "runtime"
     "sync/atomic"
-    "tools/volatile"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
 )
 
 func pause() {
@@ -61,7 +62,7 @@ func (ol *Mutex) cas(old Mutex, new Mutex) bool {
 // -----------------------------------------------------------------------------
 
 func (ol *Mutex) Load() Mutex {
-    return *ol
+    return Mutex(atomic.LoadUint64((*uint64)(ol)))
 }
 
 func Is_locked(mutex Mutex) bool {
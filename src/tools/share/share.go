@@ -24,22 +24,283 @@
 package share
 
 import (
-    "math"
+    "cmp"
+    "fmt"
+    "hash/maphash"
+    "math/bits"
+    "reflect"
+    "slices"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/dataset"
 )
 
 // -----------------------------------------------------------------------------
 
-// Common types
+// Key and value types used by the test modules
 type Key int64
 type Val int64
 
+// Position of a node relative to the keys, so that the sentinel nodes of the
+// ordered data structures do not steal any key from the domain
+type Bound int8
+
+const (
+    BOUND_MIN  Bound = -1 // Before every key (head sentinel)
+    BOUND_NONE Bound = 0  // Regular node, placed according to its key
+    BOUND_MAX  Bound = 1  // After every key (tail sentinel)
+)
+
+// Hash function, for the hash tables
+type Hasher[K comparable] func(key K) uint
+
+// Decision taken by an update function on the current value (or absence) of a key
+type Action int8
+
+const (
+    ACTION_KEEP   Action = 0 // Leave the key as it is, present or not
+    ACTION_STORE  Action = 1 // Store the returned value, inserting the key if absent
+    ACTION_DELETE Action = 2 // Delete the key, if present
+)
+
+// Atomic update of a key, implemented by each map: 'fn' may be called several
+// times, only its last decision taking effect; return the resulting value of
+// the key, and whether it is present
+type Update[K any, V any] func(key K, fn func(val V, ok bool) (V, Action)) (V, bool)
+
+// Per-instance configuration of the data structures, each data structure only
+// reading the fields it needs; a null field selects the default value
+type Options struct {
+    Capacity uint    // Expected amount of elements (hashtable_java; initial size of the buffer of deque_chaselev, power of 2)
+    Concurrency uint // Expected amount of concurrent threads, power of 2 (hashtable_java)
+    NumBuckets uint  // Amount of buckets (hashtable_copy and the list-based, split-ordered and CLHT hash tables, power of 2, initial amount for the resizable ones; and the go hash tables)
+    LevelMax uint    // Maximum level of the nodes (skip lists and priority queues)
+    Relaxation uint  // Relaxation of the relaxed priority queues (priorityqueue_spray: amount of threads the spray is tuned for, 'GOMAXPROCS' by default, 1 for an exact order; priorityqueue_multiqueue: amount of heaps, 2 x 'GOMAXPROCS' by default, 1 for an exact order)
+    AtomicSize bool  // Count the elements in striped counters, so that 'Size' does not traverse the data structure (sets)
+}
+
+// Default option values
 const (
-    KEY_MIN = math.MinInt64
-    KEY_MAX = math.MaxInt64
+    DEFAULT_CAPACITY uint = 1024
+    DEFAULT_CONCURRENCY uint = 512
+    DEFAULT_NUM_BUCKETS uint = 64
+    DEFAULT_LEVEL_MAX uint = 16
 )
 
-// Global variables
-var Capacity uint
-var Concurrency uint
-var NumBuckets uint
-var LevelMax uint
+// Amount of optimistic attempts of the OPTIK snapshots and atomic range queries, before they lock the nodes they cover
+const SNAPSHOT_RETRIES = 16
+
+// -----------------------------------------------------------------------------
+
+/** Get the default hash function for keys of type K.
+ * Integer keys are their own hash (as in ASCYLIB), strings and any other comparable keys are hashed with hash/maphash.
+ * @return Hash function
+**/
+func NewHasher[K comparable]() Hasher[K] {
+    var zero K
+    switch reflect.TypeFor[K]().Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        switch unsafe.Sizeof(zero) {
+        case 8:
+            return func(key K) uint {
+                return uint(*(*uint64)(unsafe.Pointer(&key)))
+            }
+        case 4:
+            return func(key K) uint {
+                return uint(*(*uint32)(unsafe.Pointer(&key)))
+            }
+        case 2:
+            return func(key K) uint {
+                return uint(*(*uint16)(unsafe.Pointer(&key)))
+            }
+        default:
+            return func(key K) uint {
+                return uint(*(*uint8)(unsafe.Pointer(&key)))
+            }
+        }
+    case reflect.String:
+        seed := maphash.MakeSeed()
+        return func(key K) uint {
+            return uint(maphash.String(seed, *(*string)(unsafe.Pointer(&key))))
+        }
+    default:
+        seed := maphash.MakeSeed()
+        return func(key K) uint {
+            return uint(maphash.Comparable(seed, key))
+        }
+    }
+}
+
+/** Compare two values of any type, as 'CompareAndSwap' does.
+ * @param a First value
+ * @param b Second value
+ * @return True if 'a == b', panics if their type is not comparable (like 'sync.Map.CompareAndSwap')
+**/
+func Equal[V any](a V, b V) bool {
+    return any(a) == any(b)
+}
+
+/** Insert or replace the value of a key.
+ * @param update Update of the map
+ * @param key    Key to set
+ * @param val    Value to store
+ * @return Previous value of the key, and whether it was present
+**/
+func Put[K any, V any](update Update[K, V], key K, val V) (old V, ok bool) {
+    update(key, func(cur V, found bool) (V, Action) {
+        old, ok = cur, found
+        return val, ACTION_STORE
+    })
+    return
+}
+
+/** Replace the value of a key, only if present and equal to the given one.
+ * @param update Update of the map
+ * @param key    Key to set
+ * @param old    Expected value
+ * @param new    Value to store
+ * @return Whether the value was replaced
+**/
+func CompareAndSwap[K any, V any](update Update[K, V], key K, old V, new V) (swapped bool) {
+    update(key, func(cur V, found bool) (V, Action) {
+        swapped = found && Equal(cur, old)
+        if swapped {
+            return new, ACTION_STORE
+        }
+        return cur, ACTION_KEEP
+    })
+    return
+}
+
+/** Get the value of a key, or insert the given one if absent.
+ * @param update Update of the map
+ * @param key    Key to get or set
+ * @param val    Value to store if absent
+ * @return Present value (or 'val' if inserted), and whether it was present
+**/
+func LoadOrStore[K any, V any](update Update[K, V], key K, val V) (res V, loaded bool) {
+    res, _ = update(key, func(cur V, found bool) (V, Action) {
+        loaded = found
+        if found {
+            return cur, ACTION_KEEP
+        }
+        return val, ACTION_STORE
+    })
+    return
+}
+
+/** Replace the value (or absence) of a key by the result of a function.
+ * @param update Update of the map
+ * @param key    Key to update
+ * @param fn     Function of the current value (and presence), returning the new value (and false to delete the key)
+ * @return New value of the key, and whether it is present
+**/
+func Compute[K any, V any](update Update[K, V], key K, fn func(val V, ok bool) (V, bool)) (V, bool) {
+    return update(key, func(cur V, found bool) (V, Action) {
+        val, keep := fn(cur, found)
+        if keep {
+            return val, ACTION_STORE
+        }
+        return val, ACTION_DELETE
+    })
+}
+
+// -----------------------------------------------------------------------------
+
+/** Get the value of an option, or its default value if not set.
+ * @param value Option value, null if not set
+ * @param def   Default value
+ * @return Value to use
+**/
+func Or_default(value uint, def uint) uint {
+    if value == 0 {
+        return def
+    }
+    return value
+}
+
+/** Check whether a number is a power of 2.
+ * @param x Number to check
+ * @return True if 'x' is a (non-null) power of 2
+**/
+func Is_pow2(x uint) bool {
+    return (x != 0) && (x & (x - 1)) == 0
+}
+
+/** Allocate a sentinel value, to tell apart from the boxed values by its address.
+ * A plain 'new(V)' would not do for a zero-size V, every such allocation possibly sharing the same address.
+ * @return Address of the sentinel, distinct from the address of any other value
+**/
+func New_sentinel[V any]() *V {
+    return &new(struct {
+        val V
+        _ byte
+    }).val
+}
+
+/** Get the level of a node in a perfectly balanced skip list, as built by the bulk loads.
+ * @param rank      Rank of the node in the list (from 1)
+ * @param level_max Maximum level
+ * @return Level of the node, i.e. 1 + the amount of trailing zeros of 'rank', at most 'level_max'
+**/
+func Bulk_level(rank uint, level_max uint) uint {
+    return min(uint(bits.TrailingZeros(rank)) + 1, level_max)
+}
+
+/** Check that the elements given to a bulk load are sorted by strictly increasing key.
+ * @param name  Name of the data structure
+ * @param pairs Elements to load
+ * @return Error reporting the first element out of order, nil if none
+**/
+func Check_sorted[K cmp.Ordered, V any](name string, pairs []dataset.Pair[K, V]) error {
+    for i := 1; i < len(pairs); i++ {
+        if pairs[i - 1].Key >= pairs[i].Key {
+            return Invalid_load(name, fmt.Sprintf("key %v at index %v does not follow key %v", pairs[i].Key, i, pairs[i - 1].Key))
+        }
+    }
+    return nil
+}
+
+/** Group the elements of a batch by bucket (or segment), so that each bucket is locked once.
+ * @param buckets Bucket of each element of the batch
+ * @return Indices of the elements of each bucket, by increasing bucket and keeping the order of the batch within a bucket
+**/
+func Batch_groups(buckets []uint) [][]int {
+    order := make([]int, len(buckets))
+    for i := range order {
+        order[i] = i
+    }
+    slices.SortStableFunc(order, func(a int, b int) int {
+        return cmp.Compare(buckets[a], buckets[b])
+    })
+    var groups [][]int
+    for start := 0; start < len(order); {
+        end := start + 1
+        for end < len(order) && buckets[order[end]] == buckets[order[start]] {
+            end++
+        }
+        groups = append(groups, order[start:end])
+        start = end
+    }
+    return groups
+}
+
+/** Build the error reporting an invalid option value.
+ * @param name   Name of the data structure
+ * @param option Name of the option
+ * @param value  Invalid value
+ * @param expect What the value should have been
+ * @return Error
+**/
+func Invalid_option(name string, option string, value uint, expect string) error {
+    return fmt.Errorf("%s: invalid %s %v, expected %s", name, option, value, expect)
+}
+
+/** Build the error reporting a bulk load that cannot be done.
+ * @param name   Name of the data structure
+ * @param reason Why the elements cannot be loaded
+ * @return Error
+**/
+func Invalid_load(name string, reason string) error {
+    return fmt.Errorf("%s: cannot bulk load, %s", name, reason)
+}
@@ -0,0 +1,84 @@
+/**
+ * @file   skiplist.go
+ * @author Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * @section LICENSE
+ *
+ * Copyright (c) 2016 Sébastien Rouault <sebastien.rouault@epfl.ch>
+ *
+ * ASCYLIB is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License
+ * as published by the Free Software Foundation, version 2
+ * of the License.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * @section DESCRIPTION
+ *
+ * Primitives shared by the lock-free skip-list priority queues: the random
+ * level of a new node, and the next pointers marked in their lowest bit.
+**/
+
+package skiplist
+
+import (
+    "sync/atomic"
+    "unsafe"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/xorshift"
+)
+
+// -----------------------------------------------------------------------------
+
+var state xorshift.State
+
+func Init_rand_level() {
+    state.Init()
+}
+
+/** Draw the level of a new node, each level having half the nodes of the one below.
+ * @param level_max Maximum level
+ * @return Level, between 1 and 'level_max'
+**/
+func Get_rand_level(level_max uint) uint {
+    level := uint(1)
+    for i := uint(0); i < level_max - 1; i++ {
+        if state.Intn(100) < 50 {
+            level++
+        } else {
+            break
+        }
+    }
+    return level
+}
+
+// -----------------------------------------------------------------------------
+
+func Is_marked[T any](i *T) bool {
+    return (uintptr(unsafe.Pointer(i)) & 1) != 0
+}
+
+func Unset_mark[T any](i *T) *T {
+    if !Is_marked(i) {
+        return i
+    }
+    return (*T)(unsafe.Add(unsafe.Pointer(i), -1)) // Stay in unsafe.Add, so the GC keeps seeing a pointer
+}
+
+func Set_mark[T any](i *T) *T {
+    if Is_marked(i) {
+        return i
+    }
+    return (*T)(unsafe.Add(unsafe.Pointer(i), 1))
+}
+
+func Cas[T any](p **T, old *T, new *T) bool { // Compare-and-swap a (possibly marked) pointer
+    return atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(p)), unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+func Load[T any](p **T) *T { // Atomically load a (possibly marked) pointer
+    return (*T)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(p))))
+}
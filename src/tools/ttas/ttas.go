@@ -26,7 +26,8 @@ package ttas
 import (
     "runtime"
     "sync/atomic"
-    "tools/volatile"
+
+    "github.com/LPD-EPFL/ASCYLIB-Go/src/tools/volatile"
 )
 
 const (